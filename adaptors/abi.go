@@ -0,0 +1,424 @@
+package adaptors
+
+import (
+	"encoding/json"
+	"fmt"
+	"junoplugin/models"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/NethermindEth/juno/core"
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+// Cairo types with a fixed encoding, everything else must be a struct
+// declared in the ABI or an Array/Span of a known type
+const (
+	cairoFelt252         = "core::felt252"
+	cairoContractAddress = "core::starknet::contract_address::ContractAddress"
+	cairoClassHash       = "core::starknet::class_hash::ClassHash"
+	cairoBool            = "core::bool"
+	cairoU8              = "core::integer::u8"
+	cairoU16             = "core::integer::u16"
+	cairoU32             = "core::integer::u32"
+	cairoU64             = "core::integer::u64"
+	cairoU128            = "core::integer::u128"
+	cairoI128            = "core::integer::i128"
+	cairoU256            = "core::integer::u256"
+)
+
+var cairoArrayPrefixes = []string{"core::array::Array::<", "core::array::Span::<"}
+
+// i128Min is the magnitude of the smallest i128, -2^127
+var i128Min = new(big.Int).Lsh(big.NewInt(1), 127)
+
+var uintBits = map[string]int{
+	cairoU8:  8,
+	cairoU16: 16,
+	cairoU32: 32,
+	cairoU64: 64,
+}
+
+type abiEntry struct {
+	Type    string      `json:"type"`
+	Name    string      `json:"name"`
+	Kind    string      `json:"kind"`
+	Members []abiMember `json:"members"`
+	Inputs  []abiMember `json:"inputs"`
+}
+
+type abiMember struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Kind string `json:"kind"`
+}
+
+// EventLayout is the key and data members of one event, in emission order
+type EventLayout struct {
+	Name     string
+	Selector felt.Felt
+	keys     []abiMember
+	data     []abiMember
+}
+
+// ABI holds the event layouts and structs of a Cairo contract class ABI
+type ABI struct {
+	events      map[felt.Felt]*EventLayout
+	structs     map[string][]abiMember
+	constructor []abiMember
+}
+
+// ParseABI reads a Cairo 1 ABI in the JSON form produced by the compiler.
+// Event selectors are the starknet_keccak of the event's short name, which
+// is what the compiler emits for variants nested in the contract's Event enum.
+func ParseABI(raw []byte) (*ABI, error) {
+	var entries []abiEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("parsing ABI: %w", err)
+	}
+	abi := &ABI{
+		events:  make(map[felt.Felt]*EventLayout),
+		structs: make(map[string][]abiMember),
+	}
+	for _, entry := range entries {
+		switch {
+		case entry.Type == "struct":
+			abi.structs[entry.Name] = entry.Members
+		case entry.Type == "constructor":
+			abi.constructor = entry.Inputs
+		case entry.Type == "event" && entry.Kind == "struct":
+			name := entry.Name[strings.LastIndex(entry.Name, ":")+1:]
+			layout := &EventLayout{Name: name, Selector: *Selector(name)}
+			for _, member := range entry.Members {
+				switch member.Kind {
+				case "key":
+					layout.keys = append(layout.keys, member)
+				case "data":
+					layout.data = append(layout.data, member)
+				default:
+					return nil, fmt.Errorf("event %s member %s: unsupported kind %q", name, member.Name, member.Kind)
+				}
+			}
+			abi.events[layout.Selector] = layout
+		}
+	}
+
+	// Reject unknown member types up front rather than on the first event
+	for _, layout := range abi.events {
+		for _, member := range append(append([]abiMember{}, layout.keys...), layout.data...) {
+			if err := abi.checkType(member.Type); err != nil {
+				return nil, fmt.Errorf("event %s member %s: %w", layout.Name, member.Name, err)
+			}
+		}
+	}
+	for _, input := range abi.constructor {
+		if err := abi.checkType(input.Type); err != nil {
+			return nil, fmt.Errorf("constructor input %s: %w", input.Name, err)
+		}
+	}
+	return abi, nil
+}
+
+func mustParseABI(raw []byte) *ABI {
+	abi, err := ParseABI(raw)
+	if err != nil {
+		panic(err)
+	}
+	return abi
+}
+
+func (abi *ABI) checkType(typ string) error {
+	switch typ {
+	case cairoFelt252, cairoContractAddress, cairoClassHash, cairoBool,
+		cairoU8, cairoU16, cairoU32, cairoU64, cairoU128, cairoI128, cairoU256:
+		return nil
+	}
+	if element, ok := arrayElement(typ); ok {
+		return abi.checkType(element)
+	}
+	members, ok := abi.structs[typ]
+	if !ok {
+		return fmt.Errorf("unsupported type %s", typ)
+	}
+	for _, member := range members {
+		if err := abi.checkType(member.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func arrayElement(typ string) (string, bool) {
+	for _, prefix := range cairoArrayPrefixes {
+		if strings.HasPrefix(typ, prefix) && strings.HasSuffix(typ, ">") {
+			return typ[len(prefix) : len(typ)-1], true
+		}
+	}
+	return "", false
+}
+
+// Event returns the layout of the event with the given selector
+func (abi *ABI) Event(selector *felt.Felt) (*EventLayout, bool) {
+	layout, ok := abi.events[*selector]
+	return layout, ok
+}
+
+// Decode decodes event into out, a pointer to a struct whose fields are
+// tagged `abi:"member_name"` or `abi:"member_name,optional"`. Untagged
+// fields are left alone, so one struct can decode every event sharing its
+// members. Failures are returned as *DecodeError.
+func (abi *ABI) Decode(event core.Event, out any) error {
+	if len(event.Keys) == 0 {
+		return &DecodeError{Reason: "event has no selector key"}
+	}
+	layout, ok := abi.events[*event.Keys[0]]
+	if !ok {
+		return &DecodeError{Event: event.Keys[0].String(), Reason: "unknown event selector"}
+	}
+	values := make(map[string]any, len(layout.keys)+len(layout.data))
+	if err := abi.decodeMembers(layout.keys, &feltReader{felts: event.Keys[1:], section: "keys"}, values); err != nil {
+		return err.of(layout.Name)
+	}
+	if err := abi.decodeMembers(layout.data, &feltReader{felts: event.Data, section: "data"}, values); err != nil {
+		return err.of(layout.Name)
+	}
+	if err := fill(reflect.ValueOf(out), values); err != nil {
+		return err.of(layout.Name)
+	}
+	return nil
+}
+
+// DecodeConstructor decodes constructor calldata, as carried by the UDC's
+// ContractDeployed event, into out
+func (abi *ABI) DecodeConstructor(calldata []*felt.Felt, out any) error {
+	values := make(map[string]any, len(abi.constructor))
+	if err := abi.decodeMembers(abi.constructor, &feltReader{felts: calldata, section: "calldata"}, values); err != nil {
+		return err.of("constructor")
+	}
+	if err := fill(reflect.ValueOf(out), values); err != nil {
+		return err.of("constructor")
+	}
+	return nil
+}
+
+type feltReader struct {
+	felts   []*felt.Felt
+	pos     int
+	section string
+}
+
+func (r *feltReader) next() (*felt.Felt, *DecodeError) {
+	if r.pos >= len(r.felts) {
+		return nil, decodeErrorf("%s has %d felts, layout needs more", r.section, len(r.felts))
+	}
+	f := r.felts[r.pos]
+	r.pos++
+	return f, nil
+}
+
+func (abi *ABI) decodeMembers(members []abiMember, r *feltReader, values map[string]any) *DecodeError {
+	for _, member := range members {
+		value, err := abi.decodeValue(member.Type, r)
+		if err != nil {
+			return err.in(member.Name)
+		}
+		values[member.Name] = value
+	}
+	if r.pos != len(r.felts) {
+		return decodeErrorf("%s has %d felts, layout uses %d", r.section, len(r.felts), r.pos)
+	}
+	return nil
+}
+
+// decodeValue reads one value of typ: felts for felt252, addresses and class
+// hashes, uint64 for u8-u64, BigInt for u128, i128 and u256, bool, a member
+// map for structs and a slice for arrays
+func (abi *ABI) decodeValue(typ string, r *feltReader) (any, *DecodeError) {
+	switch typ {
+	case cairoFelt252, cairoContractAddress, cairoClassHash:
+		f, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	case cairoBool:
+		f, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case f.IsZero():
+			return false, nil
+		case f.IsOne():
+			return true, nil
+		}
+		return nil, decodeErrorf("invalid bool %s", f.String())
+	case cairoU8, cairoU16, cairoU32, cairoU64:
+		f, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		if bits := f.BigInt(new(big.Int)).BitLen(); bits > uintBits[typ] {
+			return nil, decodeErrorf("%s out of range for %s", f.String(), typ)
+		}
+		return f.Uint64(), nil
+	case cairoU128:
+		f, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		value := f.BigInt(new(big.Int))
+		if value.BitLen() > 128 {
+			return nil, decodeErrorf("%s out of range for u128", f.String())
+		}
+		return models.BigInt{Int: value}, nil
+	case cairoI128:
+		f, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		// Negative values are encoded as P - |v|
+		if value := f.BigInt(new(big.Int)); value.BitLen() <= 127 {
+			return models.BigInt{Int: value}, nil
+		}
+		abs := new(felt.Felt).Sub(&felt.Zero, f).BigInt(new(big.Int))
+		if abs.Cmp(i128Min) > 0 {
+			return nil, decodeErrorf("%s out of range for i128", f.String())
+		}
+		return models.BigInt{Int: abs.Neg(abs)}, nil
+	case cairoU256:
+		low, err := abi.decodeValue(cairoU128, r)
+		if err != nil {
+			return nil, err.in("low")
+		}
+		high, err := abi.decodeValue(cairoU128, r)
+		if err != nil {
+			return nil, err.in("high")
+		}
+		value := new(big.Int).Lsh(high.(models.BigInt).Int, 128)
+		return models.BigInt{Int: value.Or(value, low.(models.BigInt).Int)}, nil
+	}
+
+	if element, ok := arrayElement(typ); ok {
+		f, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		length := f.Uint64()
+		if f.BigInt(new(big.Int)).BitLen() > 32 || length > uint64(len(r.felts)-r.pos) {
+			return nil, decodeErrorf("array length %s exceeds remaining %s", f.String(), r.section)
+		}
+		items := make([]any, 0, length)
+		for i := uint64(0); i < length; i++ {
+			item, err := abi.decodeValue(element, r)
+			if err != nil {
+				return nil, err.in(fmt.Sprintf("[%d]", i))
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+
+	members := abi.structs[typ]
+	values := make(map[string]any, len(members))
+	for _, member := range members {
+		value, err := abi.decodeValue(member.Type, r)
+		if err != nil {
+			return nil, err.in(member.Name)
+		}
+		values[member.Name] = value
+	}
+	return values, nil
+}
+
+var (
+	bigIntType  = reflect.TypeOf(models.BigInt{})
+	feltType    = reflect.TypeOf(felt.Felt{})
+	feltPtrType = reflect.TypeOf(&felt.Felt{})
+)
+
+// fill sets the tagged fields of the struct out points to from values
+func fill(out reflect.Value, values map[string]any) *DecodeError {
+	if out.Kind() == reflect.Pointer {
+		out = out.Elem()
+	}
+	if out.Kind() != reflect.Struct {
+		return decodeErrorf("cannot decode into %s", out.Type())
+	}
+	for i := 0; i < out.NumField(); i++ {
+		tag, ok := out.Type().Field(i).Tag.Lookup("abi")
+		if !ok || tag == "-" {
+			continue
+		}
+		name, option, _ := strings.Cut(tag, ",")
+		value, ok := values[name]
+		if !ok {
+			// Members added in later contract versions are tagged optional
+			// and keep their zero value on older layouts
+			if option == "optional" {
+				continue
+			}
+			return decodeErrorf("layout has no member %s", name)
+		}
+		if err := assign(out.Field(i), value); err != nil {
+			return err.in(name)
+		}
+	}
+	return nil
+}
+
+// assign converts a decoded value to the type of field: felts become hex
+// strings, BigInts or uint64s as the field asks
+func assign(field reflect.Value, value any) *DecodeError {
+	switch v := value.(type) {
+	case *felt.Felt:
+		switch {
+		case field.Kind() == reflect.String:
+			field.SetString(FeltToHexString(v.Bytes()))
+		case field.Type() == bigIntType:
+			field.Set(reflect.ValueOf(FeltToBigInt(v.Bytes())))
+		case field.Type() == feltType:
+			field.Set(reflect.ValueOf(*v))
+		case field.Type() == feltPtrType:
+			field.Set(reflect.ValueOf(v))
+		default:
+			return decodeErrorf("cannot assign felt to %s", field.Type())
+		}
+	case uint64:
+		switch {
+		case field.Kind() == reflect.Uint64:
+			field.SetUint(v)
+		case field.Type() == bigIntType:
+			field.Set(reflect.ValueOf(models.BigInt{Int: new(big.Int).SetUint64(v)}))
+		default:
+			return decodeErrorf("cannot assign integer to %s", field.Type())
+		}
+	case models.BigInt:
+		if field.Type() != bigIntType {
+			return decodeErrorf("cannot assign BigInt to %s", field.Type())
+		}
+		field.Set(reflect.ValueOf(v))
+	case bool:
+		if field.Kind() != reflect.Bool {
+			return decodeErrorf("cannot assign bool to %s", field.Type())
+		}
+		field.SetBool(v)
+	case map[string]any:
+		return fill(field, v)
+	case []any:
+		if field.Kind() != reflect.Slice {
+			return decodeErrorf("cannot assign array to %s", field.Type())
+		}
+		slice := reflect.MakeSlice(field.Type(), len(v), len(v))
+		for i, item := range v {
+			if err := assign(slice.Index(i), item); err != nil {
+				return err.in(fmt.Sprintf("[%d]", i))
+			}
+		}
+		field.Set(slice)
+	default:
+		return decodeErrorf("unsupported value %T", value)
+	}
+	return nil
+}
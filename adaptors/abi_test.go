@@ -0,0 +1,480 @@
+package adaptors
+
+import (
+	"errors"
+	"fmt"
+	"junoplugin/models"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/NethermindEth/juno/core"
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+func feltU(v uint64) *felt.Felt {
+	return new(felt.Felt).SetUint64(v)
+}
+
+func feltBig(v *big.Int) *felt.Felt {
+	return new(felt.Felt).SetBigInt(v)
+}
+
+func pow2(bits uint) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), bits)
+}
+
+func bigU(v uint64) models.BigInt {
+	return models.BigInt{Int: new(big.Int).SetUint64(v)}
+}
+
+func newEvent(name string, keys []*felt.Felt, data ...*felt.Felt) core.Event {
+	return core.Event{
+		From: feltU(0x5a),
+		Keys: append([]*felt.Felt{Selector(name)}, keys...),
+		Data: data,
+	}
+}
+
+// testABI covers the types the Pitchlake ABIs do not use
+var testABI = mustParseABI([]byte(`[
+  {
+    "type": "event",
+    "name": "test::Small",
+    "kind": "struct",
+    "members": [
+      { "name": "byte", "type": "core::integer::u8", "kind": "data" },
+      { "name": "word", "type": "core::integer::u16", "kind": "data" },
+      { "name": "dword", "type": "core::integer::u32", "kind": "data" }
+    ]
+  },
+  {
+    "type": "event",
+    "name": "test::Signed",
+    "kind": "struct",
+    "members": [
+      { "name": "value", "type": "core::integer::i128", "kind": "data" }
+    ]
+  }
+]`))
+
+type smallEvent struct {
+	Byte  uint64 `abi:"byte"`
+	Word  uint64 `abi:"word"`
+	Dword uint64 `abi:"dword"`
+}
+
+type signedEvent struct {
+	Value models.BigInt `abi:"value"`
+}
+
+func TestDecode(t *testing.T) {
+	v1, _ := Version(VersionV1)
+	v2, _ := Version(VersionV2)
+	account := feltU(0xabc)
+	negativeFive := new(felt.Felt).Sub(&felt.Zero, feltU(5))
+	maxI128 := new(big.Int).Sub(pow2(127), big.NewInt(1))
+	minI128 := new(big.Int).Neg(pow2(127))
+
+	tests := []struct {
+		name  string
+		abi   *ABI
+		event core.Event
+		out   any
+		want  any
+	}{
+		{
+			name: "v1 Deposit with u256 high halves",
+			abi:  v1.vaultABI,
+			event: newEvent("Deposit", []*felt.Felt{account},
+				feltU(100), feltU(0),
+				feltU(7), feltU(1),
+				feltU(0), feltU(2),
+			),
+			out: &LiquidityEvent{},
+			want: &LiquidityEvent{
+				Account:                   "0xabc",
+				Amount:                    bigU(100),
+				AccountUnlockedBalanceNow: models.BigInt{Int: new(big.Int).Add(pow2(128), big.NewInt(7))},
+				VaultUnlockedBalanceNow:   models.BigInt{Int: new(big.Int).Lsh(big.NewInt(2), 128)},
+			},
+		},
+		{
+			name: "v1 WithdrawalQueued leaves the optional v2 member unset",
+			abi:  v1.vaultABI,
+			event: newEvent("WithdrawalQueued", []*felt.Felt{account},
+				feltU(2500), feltU(3),
+				feltU(40), feltU(0),
+				feltU(90), feltU(0),
+			),
+			out: &WithdrawalQueuedEvent{},
+			want: &WithdrawalQueuedEvent{
+				Account:                   "0xabc",
+				Bps:                       bigU(2500),
+				RoundID:                   3,
+				AccountQueuedLiquidityNow: bigU(40),
+				VaultQueuedLiquidityNow:   bigU(90),
+			},
+		},
+		{
+			name: "v2 WithdrawalQueued with account_queued_liquidity_before",
+			abi:  v2.vaultABI,
+			event: newEvent("WithdrawalQueued", []*felt.Felt{account},
+				feltU(2500), feltU(3),
+				feltU(10), feltU(0),
+				feltU(40), feltU(0),
+				feltU(90), feltU(0),
+			),
+			out: &WithdrawalQueuedEvent{},
+			want: &WithdrawalQueuedEvent{
+				Account:                      "0xabc",
+				Bps:                          bigU(2500),
+				RoundID:                      3,
+				AccountQueuedLiquidityBefore: bigU(10),
+				AccountQueuedLiquidityNow:    bigU(40),
+				VaultQueuedLiquidityNow:      bigU(90),
+			},
+		},
+		{
+			name: "v2 OptionRoundDeployed with nested pricing data",
+			abi:  v2.vaultABI,
+			event: newEvent("OptionRoundDeployed", nil,
+				feltU(4), feltU(0xdef),
+				feltU(1000), feltU(2000), feltU(3000),
+				feltU(55), feltU(0),
+				feltU(7000),
+				feltU(12), feltU(0),
+			),
+			out: &OptionRoundDeployedEvent{},
+			want: &OptionRoundDeployedEvent{
+				RoundID:              bigU(4),
+				Address:              "0xdef",
+				AuctionStartDate:     1000,
+				AuctionEndDate:       2000,
+				OptionSettlementDate: 3000,
+				PricingData: PricingData{
+					StrikePrice:  bigU(55),
+					CapLevel:     bigU(7000),
+					ReservePrice: bigU(12),
+				},
+			},
+		},
+		{
+			name: "v1 BidPlaced with the largest u64 nonce",
+			abi:  v1.optionRoundABI,
+			event: newEvent("BidPlaced", []*felt.Felt{account},
+				feltU(0x1d),
+				feltU(5), feltU(0),
+				feltU(6), feltU(0),
+				feltU(^uint64(0)),
+			),
+			out: &BidPlacedEvent{},
+			want: &BidPlacedEvent{
+				Account:         "0xabc",
+				BidID:           "0x1d",
+				Amount:          bigU(5),
+				Price:           bigU(6),
+				BidTreeNonceNow: ^uint64(0),
+			},
+		},
+		{
+			name: "UDC ContractDeployed with bool and calldata span",
+			abi:  UDCABI,
+			event: newEvent("ContractDeployed", nil,
+				feltU(0x11), feltU(0x22), feltU(1), feltU(0x33),
+				feltU(2), feltU(0x44), feltU(0x55),
+				feltU(0x66),
+			),
+			out: &ContractDeployedEvent{},
+			want: &ContractDeployedEvent{
+				Address:   "0x11",
+				Deployer:  "0x22",
+				Unique:    true,
+				ClassHash: "0x33",
+				Calldata:  []*felt.Felt{feltU(0x44), feltU(0x55)},
+				Salt:      "0x66",
+			},
+		},
+		{
+			name:  "u8, u16 and u32 at their maximum",
+			abi:   testABI,
+			event: newEvent("Small", nil, feltU(0xff), feltU(0xffff), feltU(0xffffffff)),
+			out:   &smallEvent{},
+			want:  &smallEvent{Byte: 0xff, Word: 0xffff, Dword: 0xffffffff},
+		},
+		{
+			name:  "positive i128",
+			abi:   testABI,
+			event: newEvent("Signed", nil, feltBig(maxI128)),
+			out:   &signedEvent{},
+			want:  &signedEvent{Value: models.BigInt{Int: maxI128}},
+		},
+		{
+			name:  "negative i128",
+			abi:   testABI,
+			event: newEvent("Signed", nil, negativeFive),
+			out:   &signedEvent{},
+			want:  &signedEvent{Value: models.BigInt{Int: big.NewInt(-5)}},
+		},
+		{
+			name:  "smallest i128",
+			abi:   testABI,
+			event: newEvent("Signed", nil, new(felt.Felt).Sub(&felt.Zero, feltBig(pow2(127)))),
+			out:   &signedEvent{},
+			want:  &signedEvent{Value: models.BigInt{Int: minI128}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.abi.Decode(tt.event, tt.out); err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if got, want := fmt.Sprintf("%+v", tt.out), fmt.Sprintf("%+v", tt.want); got != want {
+				t.Errorf("Decode = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	v1, _ := Version(VersionV1)
+	v2, _ := Version(VersionV2)
+	account := feltU(0xabc)
+	u256 := func(low, high *felt.Felt) []*felt.Felt { return []*felt.Felt{low, high} }
+	deposit := func(amount []*felt.Felt) []*felt.Felt {
+		return append(append([]*felt.Felt{}, amount...), feltU(0), feltU(0), feltU(0), feltU(0))
+	}
+
+	tests := []struct {
+		name       string
+		abi        *ABI
+		event      core.Event
+		out        any
+		wantEvent  string
+		wantMember string
+		wantReason string
+	}{
+		{
+			name:       "no selector key",
+			abi:        v1.vaultABI,
+			event:      core.Event{},
+			out:        &LiquidityEvent{},
+			wantReason: "event has no selector key",
+		},
+		{
+			name:       "unknown selector",
+			abi:        v1.vaultABI,
+			event:      newEvent("NotAnEvent", nil),
+			out:        &LiquidityEvent{},
+			wantEvent:  Selector("NotAnEvent").String(),
+			wantReason: "unknown event selector",
+		},
+		{
+			name:       "truncated keys",
+			abi:        v1.vaultABI,
+			event:      newEvent("Deposit", nil, deposit(u256(feltU(1), feltU(0)))...),
+			out:        &LiquidityEvent{},
+			wantEvent:  "Deposit",
+			wantMember: "account",
+			wantReason: "keys has 0 felts, layout needs more",
+		},
+		{
+			name:       "extra keys",
+			abi:        v1.vaultABI,
+			event:      newEvent("Deposit", []*felt.Felt{account, account}, deposit(u256(feltU(1), feltU(0)))...),
+			out:        &LiquidityEvent{},
+			wantEvent:  "Deposit",
+			wantReason: "keys has 2 felts, layout uses 1",
+		},
+		{
+			name:       "truncated data inside a u256",
+			abi:        v1.vaultABI,
+			event:      newEvent("Deposit", []*felt.Felt{account}, deposit(u256(feltU(1), feltU(0)))[:5]...),
+			out:        &LiquidityEvent{},
+			wantEvent:  "Deposit",
+			wantMember: "vault_unlocked_balance_now.high",
+			wantReason: "data has 5 felts, layout needs more",
+		},
+		{
+			name:       "extra data",
+			abi:        v1.vaultABI,
+			event:      newEvent("Deposit", []*felt.Felt{account}, append(deposit(u256(feltU(1), feltU(0))), feltU(9))...),
+			out:        &LiquidityEvent{},
+			wantEvent:  "Deposit",
+			wantReason: "data has 7 felts, layout uses 6",
+		},
+		{
+			name:       "u256 low half past u128",
+			abi:        v1.vaultABI,
+			event:      newEvent("Deposit", []*felt.Felt{account}, deposit(u256(feltBig(pow2(128)), feltU(0)))...),
+			out:        &LiquidityEvent{},
+			wantEvent:  "Deposit",
+			wantMember: "amount.low",
+			wantReason: "out of range for u128",
+		},
+		{
+			name: "u128 past its range",
+			abi:  v1.vaultABI,
+			event: newEvent("WithdrawalQueued", []*felt.Felt{account},
+				feltBig(pow2(128)), feltU(3), feltU(0), feltU(0), feltU(0), feltU(0),
+			),
+			out:        &WithdrawalQueuedEvent{},
+			wantEvent:  "WithdrawalQueued",
+			wantMember: "bps",
+			wantReason: "out of range for u128",
+		},
+		{
+			name: "u64 past its range",
+			abi:  v1.optionRoundABI,
+			event: newEvent("BidPlaced", []*felt.Felt{account},
+				feltU(1), feltU(5), feltU(0), feltU(6), feltU(0), feltBig(pow2(64)),
+			),
+			out:        &BidPlacedEvent{},
+			wantEvent:  "BidPlaced",
+			wantMember: "bid_tree_nonce_now",
+			wantReason: "out of range for core::integer::u64",
+		},
+		{
+			name:       "u8 past its range",
+			abi:        testABI,
+			event:      newEvent("Small", nil, feltU(0x100), feltU(0), feltU(0)),
+			out:        &smallEvent{},
+			wantEvent:  "Small",
+			wantMember: "byte",
+			wantReason: "out of range for core::integer::u8",
+		},
+		{
+			name:       "u16 past its range",
+			abi:        testABI,
+			event:      newEvent("Small", nil, feltU(0), feltU(0x10000), feltU(0)),
+			out:        &smallEvent{},
+			wantEvent:  "Small",
+			wantMember: "word",
+			wantReason: "out of range for core::integer::u16",
+		},
+		{
+			name:       "u32 past its range",
+			abi:        testABI,
+			event:      newEvent("Small", nil, feltU(0), feltU(0), feltU(1<<32)),
+			out:        &smallEvent{},
+			wantEvent:  "Small",
+			wantMember: "dword",
+			wantReason: "out of range for core::integer::u32",
+		},
+		{
+			name:       "i128 past its range",
+			abi:        testABI,
+			event:      newEvent("Signed", nil, feltBig(pow2(127))),
+			out:        &signedEvent{},
+			wantEvent:  "Signed",
+			wantMember: "value",
+			wantReason: "out of range for i128",
+		},
+		{
+			name:       "i128 below its range",
+			abi:        testABI,
+			event:      newEvent("Signed", nil, new(felt.Felt).Sub(&felt.Zero, feltBig(new(big.Int).Add(pow2(127), big.NewInt(1))))),
+			out:        &signedEvent{},
+			wantEvent:  "Signed",
+			wantMember: "value",
+			wantReason: "out of range for i128",
+		},
+		{
+			name: "nested struct member out of range",
+			abi:  v2.vaultABI,
+			event: newEvent("OptionRoundDeployed", nil,
+				feltU(4), feltU(0xdef), feltU(1), feltU(2), feltU(3),
+				feltU(55), feltBig(pow2(128)), feltU(7000), feltU(12), feltU(0),
+			),
+			out:        &OptionRoundDeployedEvent{},
+			wantEvent:  "OptionRoundDeployed",
+			wantMember: "pricing_data.strike_price.high",
+			wantReason: "out of range for u128",
+		},
+		{
+			name: "truncated nested struct",
+			abi:  v1.optionRoundABI,
+			event: newEvent("PricingDataSet", nil,
+				feltU(55), feltU(0), feltU(7000),
+			),
+			out:        &PricingDataSetEvent{},
+			wantEvent:  "PricingDataSet",
+			wantMember: "pricing_data.reserve_price.low",
+			wantReason: "data has 3 felts, layout needs more",
+		},
+		{
+			name: "invalid bool",
+			abi:  UDCABI,
+			event: newEvent("ContractDeployed", nil,
+				feltU(0x11), feltU(0x22), feltU(2), feltU(0x33), feltU(0), feltU(0x66),
+			),
+			out:        &ContractDeployedEvent{},
+			wantEvent:  "ContractDeployed",
+			wantMember: "unique",
+			wantReason: "invalid bool 0x2",
+		},
+		{
+			name: "array length past the remaining data",
+			abi:  UDCABI,
+			event: newEvent("ContractDeployed", nil,
+				feltU(0x11), feltU(0x22), feltU(1), feltU(0x33), feltU(3), feltU(0x44), feltU(0x66),
+			),
+			out:        &ContractDeployedEvent{},
+			wantEvent:  "ContractDeployed",
+			wantMember: "calldata",
+			wantReason: "array length 0x3 exceeds remaining data",
+		},
+		{
+			name: "array ends before the members after it",
+			abi:  UDCABI,
+			event: newEvent("ContractDeployed", nil,
+				feltU(0x11), feltU(0x22), feltU(1), feltU(0x33), feltU(1), feltU(0x44),
+			),
+			out:        &ContractDeployedEvent{},
+			wantEvent:  "ContractDeployed",
+			wantMember: "salt",
+			wantReason: "data has 6 felts, layout needs more",
+		},
+		{
+			name:       "output struct without a required member",
+			abi:        v1.vaultABI,
+			event:      newEvent("Deposit", []*felt.Felt{account}, deposit(u256(feltU(1), feltU(0)))...),
+			out:        &BidPlacedEvent{},
+			wantEvent:  "Deposit",
+			wantReason: "layout has no member bid_id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.abi.Decode(tt.event, tt.out)
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("Decode error = %v, want a *DecodeError", err)
+			}
+			if decodeErr.Event != tt.wantEvent {
+				t.Errorf("Event = %q, want %q", decodeErr.Event, tt.wantEvent)
+			}
+			if decodeErr.Member != tt.wantMember {
+				t.Errorf("Member = %q, want %q", decodeErr.Member, tt.wantMember)
+			}
+			if !strings.Contains(decodeErr.Reason, tt.wantReason) {
+				t.Errorf("Reason = %q, want it to contain %q", decodeErr.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestDecodeConstructorTruncated(t *testing.T) {
+	v1, _ := Version(VersionV1)
+	var out struct {
+		FossilClientAddress string `abi:"fossil_client_address"`
+	}
+	err := v1.vaultABI.DecodeConstructor([]*felt.Felt{feltU(1)}, &out)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("DecodeConstructor error = %v, want a *DecodeError", err)
+	}
+	if decodeErr.Event != "constructor" || !strings.Contains(decodeErr.Reason, "calldata has 1 felts") {
+		t.Errorf("DecodeConstructor error = %v", decodeErr)
+	}
+}
@@ -0,0 +1,192 @@
+package adaptors
+
+import (
+	"embed"
+	"junoplugin/models"
+	"junoplugin/versions"
+
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+// Contract versions, one directory of ABIs under abi/ each (see the versions
+// package)
+const (
+	VersionV1      = versions.V1
+	VersionV2      = versions.V2
+	DefaultVersion = versions.Default
+)
+
+//go:embed abi
+var abiFiles embed.FS
+
+var (
+	UDCABI = mustParseABI(mustReadABI("abi/udc.json"))
+
+	adaptorsByVersion = newJunoAdaptors()
+)
+
+func mustReadABI(path string) []byte {
+	raw, err := abiFiles.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
+
+func newJunoAdaptors() map[string]*JunoAdaptor {
+	adaptors := make(map[string]*JunoAdaptor)
+	for _, version := range versions.All() {
+		adaptors[version] = newJunoAdaptor(version)
+	}
+	return adaptors
+}
+
+func newJunoAdaptor(version string) *JunoAdaptor {
+	return &JunoAdaptor{
+		Version:        version,
+		vaultABI:       mustParseABI(mustReadABI("abi/" + version + "/vault.json")),
+		optionRoundABI: mustParseABI(mustReadABI("abi/" + version + "/option_round.json")),
+	}
+}
+
+// Version returns the adaptor decoding events of the named contract version
+func Version(name string) (*JunoAdaptor, bool) {
+	adaptor, ok := adaptorsByVersion[name]
+	return adaptor, ok
+}
+
+// Versions lists the known contract versions
+func Versions() []string {
+	return versions.All()
+}
+
+// ContractDeployedEvent is emitted by the UDC, with the vault's constructor
+// arguments in Calldata
+type ContractDeployedEvent struct {
+	Address   string       `abi:"address"`
+	Deployer  string       `abi:"deployer"`
+	Unique    bool         `abi:"unique"`
+	ClassHash string       `abi:"classHash"`
+	Calldata  []*felt.Felt `abi:"calldata"`
+	Salt      string       `abi:"salt"`
+}
+
+type VaultConstructorArgs struct {
+	FossilClientAddress     string        `abi:"fossil_client_address"`
+	UnderlyingAsset         string        `abi:"underlying_asset"`
+	OptionRoundClassHash    string        `abi:"option_round_class_hash"`
+	Alpha                   models.BigInt `abi:"alpha"`
+	StrikeLevel             models.BigInt `abi:"strike_level"`
+	RoundTransitionDuration uint64        `abi:"round_transition_duration"`
+	AuctionDuration         uint64        `abi:"auction_duration"`
+	RoundDuration           uint64        `abi:"round_duration"`
+}
+
+// LiquidityEvent decodes both Deposit and Withdrawal
+type LiquidityEvent struct {
+	Account                   string        `abi:"account"`
+	Amount                    models.BigInt `abi:"amount"`
+	AccountUnlockedBalanceNow models.BigInt `abi:"account_unlocked_balance_now"`
+	VaultUnlockedBalanceNow   models.BigInt `abi:"vault_unlocked_balance_now"`
+}
+
+type WithdrawalQueuedEvent struct {
+	Account                      string        `abi:"account"`
+	Bps                          models.BigInt `abi:"bps"`
+	RoundID                      uint64        `abi:"round_id"`
+	AccountQueuedLiquidityBefore models.BigInt `abi:"account_queued_liquidity_before,optional"`
+	AccountQueuedLiquidityNow    models.BigInt `abi:"account_queued_liquidity_now"`
+	VaultQueuedLiquidityNow      models.BigInt `abi:"vault_queued_liquidity_now"`
+}
+
+type StashWithdrawnEvent struct {
+	Account                string        `abi:"account"`
+	Amount                 models.BigInt `abi:"amount"`
+	VaultStashedBalanceNow models.BigInt `abi:"vault_stashed_balance_now"`
+}
+
+type PricingData struct {
+	StrikePrice  models.BigInt `abi:"strike_price"`
+	CapLevel     models.BigInt `abi:"cap_level"`
+	ReservePrice models.BigInt `abi:"reserve_price"`
+}
+
+type OptionRoundDeployedEvent struct {
+	RoundID              models.BigInt `abi:"round_id"`
+	Address              string        `abi:"address"`
+	AuctionStartDate     uint64        `abi:"auction_start_date"`
+	AuctionEndDate       uint64        `abi:"auction_end_date"`
+	OptionSettlementDate uint64        `abi:"option_settlement_date"`
+	PricingData          PricingData   `abi:"pricing_data"`
+}
+
+// L1RequestFulfilledEvent is emitted when the Fossil client delivers the
+// pricing data requested for a round
+type L1RequestFulfilledEvent struct {
+	ID          string        `abi:"id"`
+	Caller      string        `abi:"caller"`
+	RoundID     models.BigInt `abi:"round_id"`
+	PricingData PricingData   `abi:"pricing_data"`
+}
+
+type PricingDataSetEvent struct {
+	PricingData PricingData `abi:"pricing_data"`
+}
+
+type AuctionStartedEvent struct {
+	StartingLiquidity models.BigInt `abi:"starting_liquidity"`
+	OptionsAvailable  models.BigInt `abi:"options_available"`
+}
+
+type AuctionEndedEvent struct {
+	OptionsSold          models.BigInt `abi:"options_sold"`
+	ClearingPrice        models.BigInt `abi:"clearing_price"`
+	UnsoldLiquidity      models.BigInt `abi:"unsold_liquidity"`
+	ClearingBidTreeNonce uint64        `abi:"clearing_bid_tree_nonce"`
+}
+
+type OptionRoundSettledEvent struct {
+	SettlementPrice models.BigInt `abi:"settlement_price"`
+	PayoutPerOption models.BigInt `abi:"payout_per_option"`
+}
+
+type BidPlacedEvent struct {
+	Account         string        `abi:"account"`
+	BidID           string        `abi:"bid_id"`
+	Amount          models.BigInt `abi:"amount"`
+	Price           models.BigInt `abi:"price"`
+	BidTreeNonceNow uint64        `abi:"bid_tree_nonce_now"`
+}
+
+type BidUpdatedEvent struct {
+	Account            string        `abi:"account"`
+	BidID              string        `abi:"bid_id"`
+	PriceIncrease      models.BigInt `abi:"price_increase"`
+	BidTreeNonceBefore uint64        `abi:"bid_tree_nonce_before"`
+	BidTreeNonceNow    uint64        `abi:"bid_tree_nonce_now"`
+}
+
+type OptionsExercisedEvent struct {
+	Account                  string        `abi:"account"`
+	TotalOptionsExercised    models.BigInt `abi:"total_options_exercised"`
+	MintableOptionsExercised models.BigInt `abi:"mintable_options_exercised"`
+	ExercisedAmount          models.BigInt `abi:"exercised_amount"`
+}
+
+type OptionsMintedEvent struct {
+	Account      string        `abi:"account"`
+	MintedAmount models.BigInt `abi:"minted_amount"`
+}
+
+type UnusedBidsRefundedEvent struct {
+	Account        string        `abi:"account"`
+	RefundedAmount models.BigInt `abi:"refunded_amount"`
+}
+
+// TransferEvent moves option tokens; the zero address is the minting or
+// burning side
+type TransferEvent struct {
+	From  string        `abi:"from"`
+	To    string        `abi:"to"`
+	Value models.BigInt `abi:"value"`
+}
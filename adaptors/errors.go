@@ -0,0 +1,69 @@
+package adaptors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DecodeError reports an event that does not match its ABI layout. The
+// decoder fills in what went wrong; the indexer adds where the event is.
+type DecodeError struct {
+	BlockNumber     uint64
+	TransactionHash string
+	EventIndex      int
+	From            string
+	// Event is the event name, or the selector when the ABI has no such event
+	Event string
+	// Member is the path of the member being decoded, e.g.
+	// pricing_data.strike_price.low, empty for errors about the whole event
+	Member string
+	Reason string
+}
+
+func decodeErrorf(format string, args ...any) *DecodeError {
+	return &DecodeError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// in prefixes the member path with the enclosing member
+func (e *DecodeError) in(member string) *DecodeError {
+	switch {
+	case e.Member == "":
+		e.Member = member
+	case strings.HasPrefix(e.Member, "["):
+		e.Member = member + e.Member
+	default:
+		e.Member = member + "." + e.Member
+	}
+	return e
+}
+
+func (e *DecodeError) of(event string) *DecodeError {
+	e.Event = event
+	return e
+}
+
+func (e *DecodeError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("decoding ")
+	if e.Event != "" {
+		sb.WriteString(e.Event)
+	} else {
+		sb.WriteString("event")
+	}
+	if e.Member != "" {
+		sb.WriteString(" member ")
+		sb.WriteString(e.Member)
+	}
+	sb.WriteString(": ")
+	sb.WriteString(e.Reason)
+	if e.TransactionHash != "" {
+		sb.WriteString(fmt.Sprintf(
+			" (block %d tx %s event %d from %s)",
+			e.BlockNumber,
+			e.TransactionHash,
+			e.EventIndex,
+			e.From,
+		))
+	}
+	return sb.String()
+}
@@ -0,0 +1,145 @@
+package adaptors
+
+import (
+	"errors"
+	"fmt"
+	"junoplugin/models"
+
+	"github.com/NethermindEth/juno/core"
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+func FeltsToHexStrings(felts []*felt.Felt) models.StringList {
+	hexStrings := make(models.StringList, len(felts))
+	for i, f := range felts {
+		hexStrings[i] = f.String()
+	}
+	return hexStrings
+}
+
+// EventToModel captures a raw event with its block and transaction coordinates
+func EventToModel(
+	block *core.Block,
+	txIndex, eventIndex int,
+	eventName string,
+) models.Event {
+	receipt := block.Receipts[txIndex]
+	event := receipt.Events[eventIndex]
+	return models.Event{
+		BlockNumber:      block.Number,
+		BlockHash:        block.Hash.String(),
+		Timestamp:        block.Timestamp,
+		TransactionHash:  receipt.TransactionHash.String(),
+		TransactionIndex: uint64(txIndex),
+		EventIndex:       uint64(eventIndex),
+		FromAddress:      event.From.String(),
+		EventName:        eventName,
+		Keys:             FeltsToHexStrings(event.Keys),
+		Data:             FeltsToHexStrings(event.Data),
+	}
+}
+
+// QuarantinedEventToModel captures an event that failed with err in handler.
+// Decode errors add the event name and the member that failed.
+func QuarantinedEventToModel(
+	block *core.Block,
+	txIndex, eventIndex int,
+	handler string,
+	err error,
+) models.QuarantinedEvent {
+	event := EventToModel(block, txIndex, eventIndex, "")
+	quarantined := models.QuarantinedEvent{
+		BlockNumber:      event.BlockNumber,
+		BlockHash:        event.BlockHash,
+		Timestamp:        event.Timestamp,
+		TransactionHash:  event.TransactionHash,
+		TransactionIndex: event.TransactionIndex,
+		EventIndex:       event.EventIndex,
+		FromAddress:      event.FromAddress,
+		Keys:             event.Keys,
+		Data:             event.Data,
+		Error:            err.Error(),
+		Handler:          handler,
+	}
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) {
+		quarantined.EventName = decodeErr.Event
+		quarantined.Member = decodeErr.Member
+	}
+	return quarantined
+}
+
+// EventFromModel rebuilds the core.Event stored in the Events table
+func EventFromModel(event models.Event) (*core.Event, error) {
+	from, err := new(felt.Felt).SetString(event.FromAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", event.FromAddress, err)
+	}
+	keys, err := hexStringsToFelts(event.Keys)
+	if err != nil {
+		return nil, err
+	}
+	data, err := hexStringsToFelts(event.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &core.Event{From: from, Keys: keys, Data: data}, nil
+}
+
+func hexStringsToFelts(hexStrings models.StringList) ([]*felt.Felt, error) {
+	felts := make([]*felt.Felt, len(hexStrings))
+	for i, hexString := range hexStrings {
+		f, err := new(felt.Felt).SetString(hexString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid felt %q: %w", hexString, err)
+		}
+		felts[i] = f
+	}
+	return felts, nil
+}
+
+// BlockFromEvents rebuilds a block holding only the stored events of a single
+// block, each at its original transaction and event index, so it can be fed
+// through the same indexing path as a block received from Juno.
+func BlockFromEvents(events []models.Event) (*core.Block, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no events to rebuild a block from")
+	}
+	blockHash, err := new(felt.Felt).SetString(events[0].BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block hash %q: %w", events[0].BlockHash, err)
+	}
+
+	var receipts []*core.TransactionReceipt
+	for _, storedEvent := range events {
+		if storedEvent.BlockNumber != events[0].BlockNumber {
+			return nil, fmt.Errorf("events span blocks %d and %d", events[0].BlockNumber, storedEvent.BlockNumber)
+		}
+		for uint64(len(receipts)) <= storedEvent.TransactionIndex {
+			receipts = append(receipts, &core.TransactionReceipt{})
+		}
+		receipt := receipts[storedEvent.TransactionIndex]
+		if receipt.TransactionHash == nil {
+			receipt.TransactionHash, err = new(felt.Felt).SetString(storedEvent.TransactionHash)
+			if err != nil {
+				return nil, fmt.Errorf("invalid transaction hash %q: %w", storedEvent.TransactionHash, err)
+			}
+		}
+		for uint64(len(receipt.Events)) <= storedEvent.EventIndex {
+			receipt.Events = append(receipt.Events, nil)
+		}
+		receipt.Events[storedEvent.EventIndex], err = EventFromModel(storedEvent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &core.Block{
+		Header: &core.Header{
+			Hash:      blockHash,
+			Number:    events[0].BlockNumber,
+			Timestamp: events[0].Timestamp,
+		},
+		Receipts: receipts,
+	}, nil
+}
@@ -2,146 +2,227 @@ package adaptors
 
 import (
 	"junoplugin/models"
-	"log"
 	"math/big"
 
 	"github.com/NethermindEth/juno/core"
 )
 
+// JunoAdaptor maps decoded Pitchlake events onto the values the indexer
+// stores, using the ABIs of one contract version
 type JunoAdaptor struct {
+	Version        string
+	vaultABI       *ABI
+	optionRoundABI *ABI
 }
 
-func (p *JunoAdaptor) ContractDeployed(event core.Event) (string, string, string, models.BigInt, models.BigInt, uint64, uint64, uint64) {
-
-	fossilClientAddress := FeltToHexString(event.Data[5].Bytes())
-	ethAddress := FeltToHexString(event.Data[6].Bytes())
-	optionRoundClassHash := FeltToHexString(event.Data[7].Bytes())
-	alpha := FeltToBigInt(event.Data[8].Bytes())
-	strikeLevel := FeltToBigInt(event.Data[9].Bytes())
-	roundTransitionDuration := event.Data[10].Uint64()
-	auctionDuration := event.Data[11].Uint64()
-	roundDuration := event.Data[12].Uint64()
-	return fossilClientAddress,
-		ethAddress,
-		optionRoundClassHash,
-		alpha,
-		strikeLevel,
-		roundTransitionDuration,
-		auctionDuration,
-		roundDuration
-}
-func (p *JunoAdaptor) PricingDataSet(event core.Event) (models.BigInt, models.BigInt, models.BigInt) {
-	strikePrice := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	capLevel := FeltToBigInt(event.Data[2].Bytes())
-	reservePrice := CombineFeltToBigInt(event.Data[4].Bytes(), event.Data[3].Bytes())
-	return strikePrice, capLevel, reservePrice
-}
-func (p *JunoAdaptor) DepositOrWithdraw(event core.Event) (string, models.BigInt, models.BigInt) {
-	lpAddress := FeltToHexString(event.Keys[1].Bytes())
-	lpUnlocked := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	vaultUnlocked := CombineFeltToBigInt(event.Data[5].Bytes(), event.Data[4].Bytes())
-	return lpAddress, lpUnlocked, vaultUnlocked
-}
-
-func (p *JunoAdaptor) WithdrawalQueued(event core.Event) (string, models.BigInt, uint64, models.BigInt, models.BigInt, models.BigInt) {
-	lpAddress := FeltToHexString(event.Keys[1].Bytes())
-	bps := FeltToBigInt(event.Data[0].Bytes())
-	roundId := event.Data[1].Uint64()
-	accountQueuedNow := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	vaultQueuedNow := CombineFeltToBigInt(event.Data[5].Bytes(), event.Data[4].Bytes())
-
-	//Change this when using new cont
-	accountQueuedBefore := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-
-	return lpAddress, bps, roundId, accountQueuedBefore, accountQueuedNow, vaultQueuedNow
-}
-
-func (p *JunoAdaptor) StashWithdrawn(event core.Event) (string, models.BigInt, models.BigInt) {
-	lpAddress := FeltToHexString(event.Keys[1].Bytes())
-	amount := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	vaultStashed := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	return lpAddress, amount, vaultStashed
-}
-
-func (p *JunoAdaptor) RoundDeployed(event core.Event) models.OptionRound {
-
-	log.Printf("event %v", event)
-	vaultAddress :=
-		event.From.String()
-	roundId := FeltToBigInt(event.Data[0].Bytes())
-	roundAddress := FeltToHexString(event.Data[1].Bytes())
-	startingBlock := event.Data[2].Uint64()
-	endingBlock := event.Data[3].Uint64()
-	settlementDate := event.Data[4].Uint64()
-	strikePrice := CombineFeltToBigInt(event.Data[6].Bytes(), event.Data[5].Bytes())
-	capLevel := FeltToBigInt(event.Data[7].Bytes())
-	reservePrice := CombineFeltToBigInt(event.Data[9].Bytes(), event.Data[8].Bytes())
+// DecodeContractDeployed decodes a UDC ContractDeployed event of any class
+func DecodeContractDeployed(event core.Event) (ContractDeployedEvent, error) {
+	var deployed ContractDeployedEvent
+	err := UDCABI.Decode(event, &deployed)
+	return deployed, err
+}
+
+// ContractDeployed decodes the vault constructor arguments of a UDC
+// ContractDeployed event
+func (p *JunoAdaptor) ContractDeployed(event core.Event) (string, string, string, models.BigInt, models.BigInt, uint64, uint64, uint64, error) {
+	deployed, err := DecodeContractDeployed(event)
+	if err != nil {
+		return "", "", "", models.BigInt{}, models.BigInt{}, 0, 0, 0, err
+	}
+	var args VaultConstructorArgs
+	if err := p.vaultABI.DecodeConstructor(deployed.Calldata, &args); err != nil {
+		return "", "", "", models.BigInt{}, models.BigInt{}, 0, 0, 0, err
+	}
+	return args.FossilClientAddress,
+		args.UnderlyingAsset,
+		args.OptionRoundClassHash,
+		args.Alpha,
+		args.StrikeLevel,
+		args.RoundTransitionDuration,
+		args.AuctionDuration,
+		args.RoundDuration,
+		nil
+}
+func (p *JunoAdaptor) PricingDataSet(event core.Event) (models.BigInt, models.BigInt, models.BigInt, error) {
+	var decoded PricingDataSetEvent
+	if err := p.optionRoundABI.Decode(event, &decoded); err != nil {
+		return models.BigInt{}, models.BigInt{}, models.BigInt{}, err
+	}
+	pricing := decoded.PricingData
+	return pricing.StrikePrice, pricing.CapLevel, pricing.ReservePrice, nil
+}
+func (p *JunoAdaptor) DepositOrWithdraw(event core.Event) (string, models.BigInt, models.BigInt, models.BigInt, error) {
+	var decoded LiquidityEvent
+	if err := p.vaultABI.Decode(event, &decoded); err != nil {
+		return "", models.BigInt{}, models.BigInt{}, models.BigInt{}, err
+	}
+	return decoded.Account, decoded.Amount, decoded.AccountUnlockedBalanceNow, decoded.VaultUnlockedBalanceNow, nil
+}
+
+func (p *JunoAdaptor) WithdrawalQueued(event core.Event) (string, models.BigInt, uint64, models.BigInt, models.BigInt, models.BigInt, error) {
+	var decoded WithdrawalQueuedEvent
+	if err := p.vaultABI.Decode(event, &decoded); err != nil {
+		return "", models.BigInt{}, 0, models.BigInt{}, models.BigInt{}, models.BigInt{}, err
+	}
+
+	accountQueuedBefore := decoded.AccountQueuedLiquidityBefore
+	if accountQueuedBefore.Int == nil {
+		// v1 vaults do not emit the amount queued before
+		accountQueuedBefore = decoded.AccountQueuedLiquidityNow
+	}
+
+	return decoded.Account,
+		decoded.Bps,
+		decoded.RoundID,
+		accountQueuedBefore,
+		decoded.AccountQueuedLiquidityNow,
+		decoded.VaultQueuedLiquidityNow,
+		nil
+}
+
+func (p *JunoAdaptor) StashWithdrawn(event core.Event) (string, models.BigInt, models.BigInt, error) {
+	var decoded StashWithdrawnEvent
+	if err := p.vaultABI.Decode(event, &decoded); err != nil {
+		return "", models.BigInt{}, models.BigInt{}, err
+	}
+	return decoded.Account, decoded.Amount, decoded.VaultStashedBalanceNow, nil
+}
+
+func (p *JunoAdaptor) RoundDeployed(event core.Event) (models.OptionRound, error) {
+	var decoded OptionRoundDeployedEvent
+	if err := p.vaultABI.Decode(event, &decoded); err != nil {
+		return models.OptionRound{}, err
+	}
 	optionRound := models.OptionRound{
-		RoundID:        roundId,
-		Address:        roundAddress,
-		VaultAddress:   vaultAddress,
-		StartDate:      startingBlock,
-		EndDate:        endingBlock,
-		SettlementDate: settlementDate,
-		StrikePrice:    strikePrice,
-		CapLevel:       capLevel,
-		ReservePrice:   reservePrice,
+		RoundID:        decoded.RoundID,
+		Address:        decoded.Address,
+		VaultAddress:   event.From.String(),
+		StartDate:      decoded.AuctionStartDate,
+		EndDate:        decoded.AuctionEndDate,
+		SettlementDate: decoded.OptionSettlementDate,
+		StrikePrice:    decoded.PricingData.StrikePrice,
+		CapLevel:       decoded.PricingData.CapLevel,
+		ReservePrice:   decoded.PricingData.ReservePrice,
 		State:          "Open",
 	}
-	return optionRound
+	return optionRound, nil
 
 }
 
-func (p *JunoAdaptor) AuctionStarted(event core.Event) (models.BigInt, models.BigInt) {
-
-	startingLiquidity := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	availableOptions := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	return availableOptions, startingLiquidity
+// L1RequestFulfilled decodes a fulfilled Fossil request; the caller fills
+// in the fossil client and the fulfilment block
+func (p *JunoAdaptor) L1RequestFulfilled(event core.Event) (models.L1Request, error) {
+	var decoded L1RequestFulfilledEvent
+	if err := p.vaultABI.Decode(event, &decoded); err != nil {
+		return models.L1Request{}, err
+	}
+	return models.L1Request{
+		VaultAddress: event.From.String(),
+		RoundID:      decoded.RoundID,
+		RequestID:    decoded.ID,
+		Caller:       decoded.Caller,
+		StrikePrice:  decoded.PricingData.StrikePrice,
+		CapLevel:     decoded.PricingData.CapLevel,
+		ReservePrice: decoded.PricingData.ReservePrice,
+	}, nil
 }
 
-func (p *JunoAdaptor) AuctionEnded(event core.Event) (models.BigInt, models.BigInt, models.BigInt, uint64, models.BigInt) {
-	optionsSold := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	clearingPrice := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	unsoldLiquidity := CombineFeltToBigInt(event.Data[5].Bytes(), event.Data[4].Bytes())
-	clearingNonce := event.Data[6].Uint64()
-	premiums := models.BigInt{Int: new(big.Int).Mul(optionsSold.Int, clearingPrice.Int)}
+func (p *JunoAdaptor) AuctionStarted(event core.Event) (models.BigInt, models.BigInt, error) {
+	var decoded AuctionStartedEvent
+	if err := p.optionRoundABI.Decode(event, &decoded); err != nil {
+		return models.BigInt{}, models.BigInt{}, err
+	}
+	return decoded.OptionsAvailable, decoded.StartingLiquidity, nil
+}
 
-	return optionsSold, clearingPrice, unsoldLiquidity, clearingNonce, premiums
+func (p *JunoAdaptor) AuctionEnded(event core.Event) (models.BigInt, models.BigInt, models.BigInt, uint64, models.BigInt, error) {
+	var decoded AuctionEndedEvent
+	if err := p.optionRoundABI.Decode(event, &decoded); err != nil {
+		return models.BigInt{}, models.BigInt{}, models.BigInt{}, 0, models.BigInt{}, err
+	}
+	premiums := models.BigInt{Int: new(big.Int).Mul(decoded.OptionsSold.Int, decoded.ClearingPrice.Int)}
+
+	return decoded.OptionsSold,
+		decoded.ClearingPrice,
+		decoded.UnsoldLiquidity,
+		decoded.ClearingBidTreeNonce,
+		premiums,
+		nil
 }
 
-func (p *JunoAdaptor) RoundSettled(event core.Event) (models.BigInt, models.BigInt) {
-	settlementPrice := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	payoutPerOption := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	return settlementPrice, payoutPerOption
+func (p *JunoAdaptor) RoundSettled(event core.Event) (models.BigInt, models.BigInt, error) {
+	var decoded OptionRoundSettledEvent
+	if err := p.optionRoundABI.Decode(event, &decoded); err != nil {
+		return models.BigInt{}, models.BigInt{}, err
+	}
+	return decoded.SettlementPrice, decoded.PayoutPerOption, nil
 }
 
-func (p *JunoAdaptor) BidPlaced(event core.Event) (models.Bid, models.OptionBuyer) {
-	bidId := event.Data[0].String()
-	bidAmount := CombineFeltToBigInt(event.Data[2].Bytes(), event.Data[1].Bytes())
-	bidPrice := CombineFeltToBigInt(event.Data[4].Bytes(), event.Data[3].Bytes())
-	treeNonce := event.Data[5].Uint64() - 1
+func (p *JunoAdaptor) BidPlaced(event core.Event) (models.Bid, models.OptionBuyer, error) {
+	var decoded BidPlacedEvent
+	if err := p.optionRoundABI.Decode(event, &decoded); err != nil {
+		return models.Bid{}, models.OptionBuyer{}, err
+	}
 
 	bid := models.Bid{
-		BuyerAddress: FeltToHexString(event.Keys[1].Bytes()),
-		BidID:        bidId,
+		BuyerAddress: decoded.Account,
+		BidID:        decoded.BidID,
 		RoundAddress: event.From.String(),
-		Amount:       bidAmount,
-		Price:        bidPrice,
-		TreeNonce:    treeNonce,
+		Amount:       decoded.Amount,
+		Price:        decoded.Price,
+		TreeNonce:    decoded.BidTreeNonceNow - 1,
 	}
 
 	buyer := models.OptionBuyer{
-		Address:      FeltToHexString(event.Keys[1].Bytes()),
+		Address:      decoded.Account,
 		RoundAddress: event.From.String(),
 	}
 
-	return bid, buyer
+	return bid, buyer, nil
+}
+
+func (p *JunoAdaptor) BidUpdated(event core.Event) (string, models.BigInt, uint64, uint64, error) {
+	var decoded BidUpdatedEvent
+	if err := p.optionRoundABI.Decode(event, &decoded); err != nil {
+		return "", models.BigInt{}, 0, 0, err
+	}
+	return decoded.BidID, decoded.PriceIncrease, decoded.BidTreeNonceBefore, decoded.BidTreeNonceNow, nil
+}
+
+// OptionsExercised returns the account, the options it exercised (minted
+// tokens and mintable options together), the mintable part and the payout
+func (p *JunoAdaptor) OptionsExercised(event core.Event) (string, models.BigInt, models.BigInt, models.BigInt, error) {
+	var decoded OptionsExercisedEvent
+	if err := p.optionRoundABI.Decode(event, &decoded); err != nil {
+		return "", models.BigInt{}, models.BigInt{}, models.BigInt{}, err
+	}
+	return decoded.Account,
+		decoded.TotalOptionsExercised,
+		decoded.MintableOptionsExercised,
+		decoded.ExercisedAmount,
+		nil
+}
+
+func (p *JunoAdaptor) OptionsMinted(event core.Event) (string, models.BigInt, error) {
+	var decoded OptionsMintedEvent
+	if err := p.optionRoundABI.Decode(event, &decoded); err != nil {
+		return "", models.BigInt{}, err
+	}
+	return decoded.Account, decoded.MintedAmount, nil
+}
+
+func (p *JunoAdaptor) UnusedBidsRefunded(event core.Event) (string, models.BigInt, error) {
+	var decoded UnusedBidsRefundedEvent
+	if err := p.optionRoundABI.Decode(event, &decoded); err != nil {
+		return "", models.BigInt{}, err
+	}
+	return decoded.Account, decoded.RefundedAmount, nil
 }
 
-func (p *JunoAdaptor) BidUpdated(event core.Event) (string, models.BigInt, uint64, uint64) {
-	bidId := event.Data[0].String()
-	price := CombineFeltToBigInt(event.Data[2].Bytes(), event.Data[1].Bytes())
-	treeNonceOld := event.Data[3].Uint64()
-	treeNonceNew := event.Data[4].Uint64()
-	return bidId, price, treeNonceOld, treeNonceNew
+func (p *JunoAdaptor) Transfer(event core.Event) (string, string, models.BigInt, error) {
+	var decoded TransferEvent
+	if err := p.optionRoundABI.Decode(event, &decoded); err != nil {
+		return "", "", models.BigInt{}, err
+	}
+	return decoded.From, decoded.To, decoded.Value, nil
 }
@@ -0,0 +1,49 @@
+package adaptors
+
+import "fmt"
+
+// Registry picks the adaptor for a vault or option round from its class
+// hash, so vaults deployed from different contract versions can be indexed
+// side by side
+type Registry struct {
+	vaults       map[string]*JunoAdaptor
+	optionRounds map[string]*JunoAdaptor
+}
+
+// NewRegistry maps normalized class hashes to contract version names
+func NewRegistry(vaultClasses, optionRoundClasses map[string]string) (*Registry, error) {
+	registry := &Registry{
+		vaults:       make(map[string]*JunoAdaptor, len(vaultClasses)),
+		optionRounds: make(map[string]*JunoAdaptor, len(optionRoundClasses)),
+	}
+	for classHash, version := range vaultClasses {
+		adaptor, ok := Version(version)
+		if !ok {
+			return nil, fmt.Errorf("vault class %s: unknown contract version %q", classHash, version)
+		}
+		registry.vaults[classHash] = adaptor
+	}
+	for classHash, version := range optionRoundClasses {
+		adaptor, ok := Version(version)
+		if !ok {
+			return nil, fmt.Errorf("option round class %s: unknown contract version %q", classHash, version)
+		}
+		registry.optionRounds[classHash] = adaptor
+	}
+	return registry, nil
+}
+
+// Vault returns the adaptor of a vault class, false if the class is not indexed
+func (r *Registry) Vault(classHash string) (*JunoAdaptor, bool) {
+	adaptor, ok := r.vaults[classHash]
+	return adaptor, ok
+}
+
+// OptionRound returns the adaptor pinned to an option round class, or the
+// adaptor of the vault deploying it when the class is not listed
+func (r *Registry) OptionRound(classHash string, vault *JunoAdaptor) *JunoAdaptor {
+	if adaptor, ok := r.optionRounds[classHash]; ok {
+		return adaptor
+	}
+	return vault
+}
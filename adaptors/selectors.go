@@ -0,0 +1,105 @@
+package adaptors
+
+import (
+	"github.com/NethermindEth/juno/core"
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+// EventKind identifies an event the indexer handles by its selector, shared
+// by the UDC, vault and option round dispatch
+type EventKind uint8
+
+const (
+	EventUnknown EventKind = iota
+
+	// UDC events
+	EventContractDeployed
+
+	// Vault events
+	EventDeposit
+	EventWithdrawal
+	EventWithdrawalQueued
+	EventStashWithdrawn
+	EventOptionRoundDeployed
+	EventL1RequestFulfilled
+
+	// Option round events
+	EventPricingDataSet
+	EventAuctionStarted
+	EventBidPlaced
+	EventBidUpdated
+	EventAuctionEnded
+	EventOptionRoundSettled
+	EventOptionsExercised
+	EventOptionsMinted
+	EventUnusedBidsRefunded
+	// Option rounds are ERC20 tokens of their options
+	EventTransfer
+	EventApproval
+)
+
+var eventKindNames = map[EventKind]string{
+	EventContractDeployed:    "ContractDeployed",
+	EventDeposit:             "Deposit",
+	EventWithdrawal:          "Withdrawal",
+	EventWithdrawalQueued:    "WithdrawalQueued",
+	EventStashWithdrawn:      "StashWithdrawn",
+	EventOptionRoundDeployed: "OptionRoundDeployed",
+	EventL1RequestFulfilled:  "L1RequestFulfilled",
+	EventPricingDataSet:      "PricingDataSet",
+	EventAuctionStarted:      "AuctionStarted",
+	EventBidPlaced:           "BidPlaced",
+	EventBidUpdated:          "BidUpdated",
+	EventAuctionEnded:        "AuctionEnded",
+	EventOptionRoundSettled:  "OptionRoundSettled",
+	EventOptionsExercised:    "OptionsExercised",
+	EventOptionsMinted:       "OptionsMinted",
+	EventUnusedBidsRefunded:  "UnusedBidsRefunded",
+	EventTransfer:            "Transfer",
+	EventApproval:            "Approval",
+}
+
+// selectorKinds is hashed once at init so dispatch is a single map lookup
+// on the event's first key
+var selectorKinds = func() map[felt.Felt]EventKind {
+	kinds := make(map[felt.Felt]EventKind, len(eventKindNames))
+	for kind, name := range eventKindNames {
+		kinds[*Selector(name)] = kind
+	}
+	return kinds
+}()
+
+// Selector returns the starknet_keccak selector of an event name
+func Selector(eventName string) *felt.Felt {
+	selector, err := new(felt.Felt).SetString(Keccak256(eventName))
+	if err != nil {
+		panic(err)
+	}
+	return selector
+}
+
+// EventKindOf looks up the kind of an event from its selector key,
+// EventUnknown if it has none or the selector is not handled
+func EventKindOf(event *core.Event) EventKind {
+	if len(event.Keys) == 0 {
+		return EventUnknown
+	}
+	return selectorKinds[*event.Keys[0]]
+}
+
+func (k EventKind) String() string {
+	if name, ok := eventKindNames[k]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// IsVault reports whether the kind is emitted by vaults
+func (k EventKind) IsVault() bool {
+	return k >= EventDeposit && k <= EventL1RequestFulfilled
+}
+
+// IsRound reports whether the kind is emitted by option rounds
+func (k EventKind) IsRound() bool {
+	return k >= EventPricingDataSet && k <= EventApproval
+}
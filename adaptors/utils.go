@@ -57,26 +57,6 @@ func DecimalStringToHexString(decimalString string) (string, error) {
 	return "0x" + hexString, nil
 }
 
-var vaultEventNames = []string{
-	"Deposit",
-	"Withdrawal",
-	"WithdrawalQueued",
-	"StashWithdrawn",
-	"OptionRoundDeployed",
-	"L1RequestFulfilled",
-}
-var roundEventNames = []string{
-	"PricingDataSet",
-	"AuctionStarted",
-	"BidPlaced",
-	"BidUpdated",
-	"AuctionEnded",
-	"OptionRoundSettled",
-	"OptionsExercised",
-	"OptionsMinted",
-	"UnusedBidsRefunded",
-}
-
 // keccak256 function to hash the event name
 func Keccak256(eventName string) string {
 	hasher := sha3.NewLegacyKeccak256()
@@ -98,22 +78,3 @@ func Keccak256(eventName string) string {
 	// Convert the masked hash to a hexadecimal string with "0x" prefix
 	return "0x" + hashInt.Text(16)
 }
-
-// DecodeEventName decodes the event name from the keys of a StarkNet event
-func DecodeEventNameRound(eventKey string) (string, error) {
-	for _, name := range roundEventNames {
-		if Keccak256(name) == eventKey {
-			return name, nil
-		}
-	}
-	return "", fmt.Errorf("event name not found for key: %s", eventKey)
-}
-
-func DecodeEventNameVault(eventKey string) (string, error) {
-	for _, name := range vaultEventNames {
-		if Keccak256(name) == eventKey {
-			return name, nil
-		}
-	}
-	return "", fmt.Errorf("event name not found for key: %s", eventKey)
-}
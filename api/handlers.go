@@ -0,0 +1,611 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"junoplugin/db"
+	"junoplugin/hexutil"
+	"junoplugin/metrics"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+	// defaultMetricsWindow is how many of a vault's latest settled rounds the
+	// rolling metrics cover when the client does not say
+	defaultMetricsWindow = 10
+)
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /vaults", s.listVaults)
+	s.mux.HandleFunc("GET /vaults/{vault}", s.getVault)
+	s.mux.HandleFunc("GET /vaults/{vault}/state", s.getVaultStateAt)
+	s.mux.HandleFunc("GET /vaults/{vault}/history", s.getVaultHistory)
+	s.mux.HandleFunc("GET /vaults/{vault}/rounds", s.listVaultRounds)
+	s.mux.HandleFunc("GET /vaults/{vault}/lps", s.listVaultLPs)
+	s.mux.HandleFunc("GET /vaults/{vault}/lps/{lp}", s.getLPPosition)
+	s.mux.HandleFunc("GET /vaults/{vault}/lps/{lp}/state", s.getLPStateAt)
+	s.mux.HandleFunc("GET /vaults/{vault}/lps/{lp}/history", s.getLPHistory)
+	s.mux.HandleFunc("GET /vaults/{vault}/lps/{lp}/actions", s.listVaultLPActions)
+	s.mux.HandleFunc("GET /vaults/{vault}/actions", s.listVaultActions)
+	s.mux.HandleFunc("GET /vaults/{vault}/metrics", s.getVaultMetrics)
+	s.mux.HandleFunc("GET /vaults/{vault}/round-metrics", s.listVaultRoundMetrics)
+	s.mux.HandleFunc("GET /rounds/{round}", s.getRound)
+	s.mux.HandleFunc("GET /rounds/{round}/l1-request", s.getRoundL1Request)
+	s.mux.HandleFunc("GET /rounds/{round}/metrics", s.getRoundMetrics)
+	s.mux.HandleFunc("GET /rounds/{round}/bids", s.listRoundBids)
+	s.mux.HandleFunc("GET /rounds/{round}/buyers", s.listRoundBuyers)
+	s.mux.HandleFunc("GET /rounds/{round}/refund-mismatches", s.listRefundMismatches)
+	s.mux.HandleFunc("GET /rounds/{round}/holders", s.listRoundHolders)
+	s.mux.HandleFunc("GET /rounds/{round}/holders/{holder}", s.getHolder)
+	s.mux.HandleFunc("GET /rounds/{round}/buyers/{buyer}", s.getBuyer)
+	s.mux.HandleFunc("GET /rounds/{round}/buyers/{buyer}/bids", s.listBuyerBids)
+	s.mux.HandleFunc("GET /lps/{lp}/positions", s.listLPPositions)
+	s.mux.HandleFunc("GET /lps/{lp}/queued", s.listLPQueuedLiquidity)
+	s.mux.HandleFunc("GET /lps/{lp}/actions", s.listLPActions)
+}
+
+// requestError is returned to the client as a 400 with its message
+type requestError struct {
+	msg string
+}
+
+func (e *requestError) Error() string {
+	return e.msg
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &requestError{msg: fmt.Sprintf(format, args...)}
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.log.Printf("API: writing response: %v", err)
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if reqErr, ok := err.(*requestError); ok {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": reqErr.msg})
+		return
+	}
+	s.log.Printf("API: %s %s: %v", r.Method, r.URL.Path, err)
+	s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+}
+
+// writeResult writes a single record, or a 404 when the query found nothing
+func writeResult[T any](s *Server, w http.ResponseWriter, r *http.Request, record *T, err error) {
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if record == nil {
+		s.writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	s.writeJSON(w, http.StatusOK, record)
+}
+
+// listResponse wraps a page of results with the window that produced it
+type listResponse[T any] struct {
+	Items  []T `json:"items"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+func writeList[T any](s *Server, w http.ResponseWriter, r *http.Request, page db.Page, items []T, err error) {
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if items == nil {
+		items = []T{}
+	}
+	s.writeJSON(w, http.StatusOK, listResponse[T]{Items: items, Limit: page.Limit, Offset: page.Offset})
+}
+
+// pathAddress reads a path parameter and normalizes it to the "0x" + lowercase
+// hex form, without leading zeros, that addresses are stored in
+func pathAddress(r *http.Request, name string) (string, error) {
+	address, err := hexutil.NormalizeHexString(r.PathValue(name))
+	if err != nil {
+		return "", badRequest("invalid %s address: %v", name, err)
+	}
+	return address, nil
+}
+
+func pageParams(r *http.Request) (db.Page, error) {
+	page := db.Page{Limit: defaultLimit}
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return page, badRequest("limit must be between 1 and %d", maxLimit)
+		}
+		page.Limit = limit
+	}
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return page, badRequest("offset must be a non-negative number")
+		}
+		page.Offset = offset
+	}
+	return page, nil
+}
+
+func blockParam(r *http.Request, name string, required bool) (uint64, bool, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		if required {
+			return 0, false, badRequest("%s is required", name)
+		}
+		return 0, false, nil
+	}
+	block, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, false, badRequest("%s must be a block number", name)
+	}
+	return block, true, nil
+}
+
+// blockRange reads the from/to query parameters of the history endpoints. to
+// defaults to the last indexed block.
+func (s *Server) blockRange(r *http.Request) (uint64, uint64, error) {
+	from, _, err := blockParam(r, "from", false)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, ok, err := blockParam(r, "to", false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !ok {
+		checkpoint, err := s.db.GetCheckpoint()
+		if err != nil {
+			return 0, 0, err
+		}
+		if checkpoint != nil {
+			to = checkpoint.BlockNumber
+		}
+	}
+	if to < from {
+		return 0, 0, badRequest("to must not be before from")
+	}
+	return from, to, nil
+}
+
+func (s *Server) listVaults(w http.ResponseWriter, r *http.Request) {
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	vaults, err := s.db.ListVaults(page)
+	writeList(s, w, r, page, vaults, err)
+}
+
+func (s *Server) getVault(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	state, err := s.db.GetVault(vault)
+	writeResult(s, w, r, state, err)
+}
+
+func (s *Server) getVaultStateAt(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	block, _, err := blockParam(r, "block", true)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	state, err := s.db.GetVaultStateAt(vault, block)
+	writeResult(s, w, r, state, err)
+}
+
+func (s *Server) getVaultHistory(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	from, to, err := s.blockRange(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	history, err := s.db.GetVaultStateHistory(vault, from, to, page)
+	writeList(s, w, r, page, history, err)
+}
+
+func (s *Server) listVaultRounds(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	rounds, err := s.db.ListOptionRoundsForVault(vault, page)
+	writeList(s, w, r, page, rounds, err)
+}
+
+func (s *Server) listVaultRoundMetrics(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	roundMetrics, err := s.db.ListRoundMetricsForVault(vault, page)
+	writeList(s, w, r, page, roundMetrics, err)
+}
+
+// getVaultMetrics aggregates the metrics of the vault's latest settled
+// rounds, ?rounds= of them
+func (s *Server) getVaultMetrics(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	window := defaultMetricsWindow
+	if raw := r.URL.Query().Get("rounds"); raw != "" {
+		window, err = strconv.Atoi(raw)
+		if err != nil || window < 1 || window > maxLimit {
+			s.writeError(w, r, badRequest("rounds must be between 1 and %d", maxLimit))
+			return
+		}
+	}
+	roundMetrics, err := s.db.ListRoundMetricsForVault(vault, db.Page{Limit: window})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	aggregate := metrics.ForVault(vault, roundMetrics)
+	writeResult(s, w, r, &aggregate, nil)
+}
+
+func (s *Server) listVaultLPs(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	lps, err := s.db.ListLPPositionsForVault(vault, page)
+	writeList(s, w, r, page, lps, err)
+}
+
+func (s *Server) getLPPosition(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	lp, err := pathAddress(r, "lp")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	position, err := s.db.GetLPPosition(vault, lp)
+	writeResult(s, w, r, position, err)
+}
+
+func (s *Server) getLPStateAt(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	lp, err := pathAddress(r, "lp")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	block, _, err := blockParam(r, "block", true)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	state, err := s.db.GetLPStateAt(vault, lp, block)
+	writeResult(s, w, r, state, err)
+}
+
+func (s *Server) getLPHistory(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	lp, err := pathAddress(r, "lp")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	from, to, err := s.blockRange(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	history, err := s.db.GetLPStateHistory(vault, lp, from, to, page)
+	writeList(s, w, r, page, history, err)
+}
+
+func (s *Server) getRound(w http.ResponseWriter, r *http.Request) {
+	round, err := pathAddress(r, "round")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	block, atBlock, err := blockParam(r, "block", false)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if atBlock {
+		state, err := s.db.GetOptionRoundAt(round, block)
+		writeResult(s, w, r, state, err)
+		return
+	}
+	state, err := s.db.GetOptionRound(round)
+	writeResult(s, w, r, state, err)
+}
+
+// getRoundL1Request returns the Fossil request that delivered the round's
+// pricing data
+func (s *Server) getRoundL1Request(w http.ResponseWriter, r *http.Request) {
+	address, err := pathAddress(r, "round")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	round, err := s.db.GetOptionRound(address)
+	if err != nil || round == nil {
+		writeResult(s, w, r, round, err)
+		return
+	}
+	request, err := s.db.GetL1Request(round.VaultAddress, round.RoundID)
+	writeResult(s, w, r, request, err)
+}
+
+func (s *Server) getRoundMetrics(w http.ResponseWriter, r *http.Request) {
+	round, err := pathAddress(r, "round")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	roundMetrics, err := s.db.GetRoundMetrics(round)
+	writeResult(s, w, r, roundMetrics, err)
+}
+
+func (s *Server) listRoundBids(w http.ResponseWriter, r *http.Request) {
+	round, err := pathAddress(r, "round")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	bids, err := s.db.ListBidsForRound(round, page)
+	writeList(s, w, r, page, bids, err)
+}
+
+func (s *Server) listRoundBuyers(w http.ResponseWriter, r *http.Request) {
+	round, err := pathAddress(r, "round")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	buyers, err := s.db.ListOptionBuyersForRound(round, page)
+	writeList(s, w, r, page, buyers, err)
+}
+
+func (s *Server) listRefundMismatches(w http.ResponseWriter, r *http.Request) {
+	round, err := pathAddress(r, "round")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	buyers, err := s.db.ListRefundMismatches(round, page)
+	writeList(s, w, r, page, buyers, err)
+}
+
+func (s *Server) listRoundHolders(w http.ResponseWriter, r *http.Request) {
+	round, err := pathAddress(r, "round")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	holders, err := s.db.ListOptionHoldersForRound(round, page)
+	writeList(s, w, r, page, holders, err)
+}
+
+func (s *Server) getHolder(w http.ResponseWriter, r *http.Request) {
+	round, err := pathAddress(r, "round")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	holder, err := pathAddress(r, "holder")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	block, atBlock, err := blockParam(r, "block", false)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if atBlock {
+		state, err := s.db.GetOptionHolderAt(round, holder, block)
+		writeResult(s, w, r, state, err)
+		return
+	}
+	state, err := s.db.GetOptionHolder(round, holder)
+	writeResult(s, w, r, state, err)
+}
+
+func (s *Server) getBuyer(w http.ResponseWriter, r *http.Request) {
+	round, err := pathAddress(r, "round")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	buyer, err := pathAddress(r, "buyer")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	state, err := s.db.GetOptionBuyer(round, buyer)
+	writeResult(s, w, r, state, err)
+}
+
+func (s *Server) listBuyerBids(w http.ResponseWriter, r *http.Request) {
+	round, err := pathAddress(r, "round")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	buyer, err := pathAddress(r, "buyer")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	bids, err := s.db.ListBidsForBuyer(round, buyer, page)
+	writeList(s, w, r, page, bids, err)
+}
+
+func (s *Server) listLPPositions(w http.ResponseWriter, r *http.Request) {
+	lp, err := pathAddress(r, "lp")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	positions, err := s.db.ListLPPositions(lp, page)
+	writeList(s, w, r, page, positions, err)
+}
+
+func (s *Server) listLPQueuedLiquidity(w http.ResponseWriter, r *http.Request) {
+	lp, err := pathAddress(r, "lp")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	queued, err := s.db.ListQueuedLiquidityForLP(lp, page)
+	writeList(s, w, r, page, queued, err)
+}
+
+func (s *Server) listVaultActions(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	actions, err := s.db.ListVaultLPActions(vault, page)
+	writeList(s, w, r, page, actions, err)
+}
+
+func (s *Server) listVaultLPActions(w http.ResponseWriter, r *http.Request) {
+	vault, err := pathAddress(r, "vault")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	lp, err := pathAddress(r, "lp")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	actions, err := s.db.ListLPActions(vault, lp, page)
+	writeList(s, w, r, page, actions, err)
+}
+
+func (s *Server) listLPActions(w http.ResponseWriter, r *http.Request) {
+	lp, err := pathAddress(r, "lp")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	actions, err := s.db.ListLPActions("", lp, page)
+	writeList(s, w, r, page, actions, err)
+}
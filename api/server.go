@@ -0,0 +1,62 @@
+// Package api serves the indexed Pitchlake state over a read-only HTTP/JSON
+// API so consumers don't need direct access to the Postgres schema.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"junoplugin/db"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+type Server struct {
+	db     *db.DB
+	log    *log.Logger
+	server *http.Server
+	mux    *http.ServeMux
+}
+
+func New(database *db.DB, port int, logger *log.Logger) *Server {
+	s := &Server{
+		db:  database,
+		log: logger,
+		mux: http.NewServeMux(),
+	}
+	s.routes()
+	s.server = &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Handle mounts an additional handler, such as the GraphQL endpoint, on the
+// API server
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start binds the port synchronously, so a port clash fails plugin Init, and
+// then serves in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("starting API server: %w", err)
+	}
+	s.log.Printf("Serving Pitchlake API on %s", listener.Addr())
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Printf("API server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"junoplugin/hexutil"
+	"junoplugin/notify"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+const (
+	subscriptionBuffer = 256
+	writeTimeout       = 10 * time.Second
+)
+
+// clientMessage is sent by WebSocket clients to manage their subscriptions:
+//
+//	{"type": "subscribe", "id": "lp", "filter": {"kinds": ["lp"], "vault": "0x..", "account": "0x.."}}
+//	{"type": "unsubscribe", "id": "lp"}
+type clientMessage struct {
+	Type   string        `json:"type"`
+	ID     string        `json:"id"`
+	Filter notify.Filter `json:"filter"`
+}
+
+// serverMessage is sent to WebSocket clients. Type is "subscribed",
+// "unsubscribed", "update" or "error".
+type serverMessage struct {
+	Type   string         `json:"type"`
+	ID     string         `json:"id,omitempty"`
+	Update *notify.Update `json:"update,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// ServeUpdates mounts the WebSocket push gateway on /ws, streaming the
+// listener's row updates to each client according to its subscriptions
+func (s *Server) ServeUpdates(listener *notify.Listener, allowedOrigins []string) {
+	s.mux.HandleFunc("GET /ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: allowedOrigins})
+		if err != nil {
+			// Accept has already written the error response
+			return
+		}
+		defer conn.CloseNow()
+		client := &wsClient{
+			conn:          conn,
+			listener:      listener,
+			subscriptions: make(map[string]*notify.Subscription),
+		}
+		err = client.serve(r.Context())
+		if status := websocket.CloseStatus(err); status != websocket.StatusNormalClosure && status != websocket.StatusGoingAway {
+			s.log.Printf("WebSocket client %s: %v", r.RemoteAddr, err)
+		}
+	})
+}
+
+type wsClient struct {
+	conn          *websocket.Conn
+	listener      *notify.Listener
+	mu            sync.Mutex
+	subscriptions map[string]*notify.Subscription
+}
+
+func (c *wsClient) serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer c.closeSubscriptions()
+	for {
+		var msg clientMessage
+		if err := wsjson.Read(ctx, c.conn, &msg); err != nil {
+			return err
+		}
+		switch msg.Type {
+		case "subscribe":
+			if err := c.subscribe(ctx, msg.ID, msg.Filter); err != nil {
+				c.write(ctx, serverMessage{Type: "error", ID: msg.ID, Error: err.Error()})
+				continue
+			}
+			c.write(ctx, serverMessage{Type: "subscribed", ID: msg.ID})
+		case "unsubscribe":
+			c.unsubscribe(msg.ID)
+			c.write(ctx, serverMessage{Type: "unsubscribed", ID: msg.ID})
+		default:
+			c.write(ctx, serverMessage{Type: "error", ID: msg.ID, Error: fmt.Sprintf("unknown message type %q", msg.Type)})
+		}
+	}
+}
+
+func (c *wsClient) subscribe(ctx context.Context, id string, filter notify.Filter) error {
+	if id == "" {
+		return fmt.Errorf("subscription id is required")
+	}
+	filter, err := normalizeFilter(filter)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.subscriptions[id]; ok {
+		return fmt.Errorf("subscription %q already exists", id)
+	}
+	sub := c.listener.Subscribe(filter, subscriptionBuffer)
+	c.subscriptions[id] = sub
+	go func() {
+		for update := range sub.C {
+			if err := c.write(ctx, serverMessage{Type: "update", ID: id, Update: &update}); err != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *wsClient) unsubscribe(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sub, ok := c.subscriptions[id]; ok {
+		sub.Close()
+		delete(c.subscriptions, id)
+	}
+}
+
+func (c *wsClient) closeSubscriptions() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, sub := range c.subscriptions {
+		sub.Close()
+		delete(c.subscriptions, id)
+	}
+}
+
+func (c *wsClient) write(ctx context.Context, msg serverMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	return wsjson.Write(ctx, c.conn, msg)
+}
+
+// normalizeFilter validates the subscription kinds and normalizes its
+// addresses to the form they are stored in
+func normalizeFilter(filter notify.Filter) (notify.Filter, error) {
+	for _, kind := range filter.Kinds {
+		switch kind {
+		case notify.KindVault, notify.KindLP, notify.KindOptionRound, notify.KindOptionBuyer, notify.KindBid:
+		default:
+			return filter, fmt.Errorf("unknown kind %q", kind)
+		}
+	}
+	for name, address := range map[string]*string{
+		"vault":   &filter.Vault,
+		"round":   &filter.Round,
+		"account": &filter.Account,
+	} {
+		if *address == "" {
+			continue
+		}
+		normalized, err := hexutil.NormalizeHexString(*address)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s address: %w", name, err)
+		}
+		*address = normalized
+	}
+	return filter, nil
+}
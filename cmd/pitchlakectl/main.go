@@ -0,0 +1,40 @@
+// Command pitchlakectl runs maintenance tasks against the Pitchlake indexer
+// database. It reads the same configuration as the plugin and must be run from
+// the repository root so the migrations in db/migrations are found.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: pitchlakectl <command>
+
+commands:
+  rebuild              truncate the derived tables and replay the Events table into them
+  retry-quarantined    rebuild with the quarantined vault and round events replayed in place
+`)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "rebuild":
+		err = rebuild()
+	case "retry-quarantined":
+		err = retryQuarantined()
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
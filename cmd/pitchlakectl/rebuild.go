@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"junoplugin/adaptors"
+	"junoplugin/config"
+	"junoplugin/db"
+	"junoplugin/indexer"
+	"junoplugin/models"
+	"log"
+	"sort"
+
+	"github.com/NethermindEth/juno/core"
+)
+
+// rebuild recomputes VaultStates, Liquidity_Providers, Option_Rounds,
+// Option_Buyers, Bids, Queued_Liquidity, Option_Holders, L1_Requests,
+// LP_Actions and Round_Metrics from the raw event log in a single
+// transaction, so a failed replay leaves the previous state untouched. The
+// change journal is regenerated along the way so recent blocks stay
+// revertible. The plugin must be stopped while it runs.
+func rebuild() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	database, err := db.Init(cfg.DBURL)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	idx, err := indexer.New(database, cfg, false)
+	if err != nil {
+		return err
+	}
+	return database.WithBlockTx(func(tx *db.DB) error {
+		_, err := replay(tx, idx, cfg.JournalRetention, nil)
+		return err
+	})
+}
+
+// replay truncates the derived tables and replays the Events table into them
+// in block order. The quarantined events in retry are merged in at their
+// original coordinates, each under a savepoint: an event that applies is
+// added to Events and leaves the quarantine, one that still fails is rolled
+// back and keeps its row with the new error.
+func replay(tx *db.DB, idx *indexer.Indexer, journalRetention uint64, retry []models.QuarantinedEvent) (retryResult, error) {
+	retrier := newReplayRetrier(tx, retry)
+	idx.SetRetrier(retrier)
+	defer idx.SetRetrier(nil)
+	if err := tx.TruncateDerivedTables(); err != nil {
+		return retrier.result, err
+	}
+	blockNumbers, err := tx.GetEventBlockNumbers()
+	if err != nil {
+		return retrier.result, err
+	}
+	for blockNumber := range retrier.byBlock {
+		blockNumbers = append(blockNumbers, blockNumber)
+	}
+	blockNumbers = uniqueSorted(blockNumbers)
+
+	for _, blockNumber := range blockNumbers {
+		events, err := tx.GetEventsForBlock(blockNumber)
+		if err != nil {
+			return retrier.result, err
+		}
+		quarantined := retrier.byBlock[blockNumber]
+		for _, q := range quarantined {
+			events = append(events, q.Event())
+		}
+		sort.SliceStable(events, func(i, j int) bool {
+			if events[i].TransactionIndex != events[j].TransactionIndex {
+				return events[i].TransactionIndex < events[j].TransactionIndex
+			}
+			return events[i].EventIndex < events[j].EventIndex
+		})
+		block, err := adaptors.BlockFromEvents(events)
+		if err != nil {
+			return retrier.result, fmt.Errorf("rebuilding block %d: %w", blockNumber, err)
+		}
+		if err := tx.SetJournalBlock(blockNumber); err != nil {
+			return retrier.result, err
+		}
+		for _, event := range events {
+			txIndex, index := int(event.TransactionIndex), int(event.EventIndex)
+			if _, isRetry := quarantined[coordinates{event.TransactionIndex, event.EventIndex}]; isRetry {
+				// Constructor events of a vault deployed further down the
+				// transaction are retried by its ContractDeployed instead
+				if _, err := idx.RetryEvent(block, txIndex, index); err != nil {
+					return retrier.result, err
+				}
+				continue
+			}
+			if err := idx.ProcessEvent(block, txIndex, index); err != nil {
+				return retrier.result, fmt.Errorf("replaying block %d: %w", blockNumber, err)
+			}
+		}
+	}
+	if len(blockNumbers) > 0 && blockNumbers[len(blockNumbers)-1] > journalRetention {
+		if err := tx.PruneJournal(blockNumbers[len(blockNumbers)-1] - journalRetention); err != nil {
+			return retrier.result, err
+		}
+	}
+	retrier.countUntracked()
+	log.Printf("Rebuilt derived state from %d blocks", len(blockNumbers))
+	return retrier.result, nil
+}
+
+// retryResult counts what happened to the quarantined events of a replay
+type retryResult struct {
+	applied, failed, untracked, dropped int
+}
+
+// coordinates locate an event in its block
+type coordinates struct {
+	txIndex, index uint64
+}
+
+// replayRetrier is the indexer.Retrier of a replay: it applies each
+// quarantined event under a savepoint and moves it to Events once it applies
+type replayRetrier struct {
+	tx        *db.DB
+	byBlock   map[uint64]map[coordinates]models.QuarantinedEvent
+	attempted map[uint64]bool
+	result    retryResult
+}
+
+func newReplayRetrier(tx *db.DB, retry []models.QuarantinedEvent) *replayRetrier {
+	r := &replayRetrier{
+		tx:        tx,
+		byBlock:   make(map[uint64]map[coordinates]models.QuarantinedEvent),
+		attempted: make(map[uint64]bool),
+	}
+	for _, q := range retry {
+		if r.byBlock[q.BlockNumber] == nil {
+			r.byBlock[q.BlockNumber] = make(map[coordinates]models.QuarantinedEvent)
+		}
+		r.byBlock[q.BlockNumber][coordinates{q.TransactionIndex, q.EventIndex}] = q
+	}
+	return r
+}
+
+func (r *replayRetrier) Retrying(block *core.Block, txIndex, index int) (models.QuarantinedEvent, bool) {
+	q, ok := r.byBlock[block.Number][coordinates{uint64(txIndex), uint64(index)}]
+	if !ok || r.attempted[q.ID] {
+		return models.QuarantinedEvent{}, false
+	}
+	return q, true
+}
+
+func (r *replayRetrier) Retry(q models.QuarantinedEvent, eventName string, apply func() error) error {
+	r.attempted[q.ID] = true
+	if err := r.tx.SavePoint("retry"); err != nil {
+		return err
+	}
+	retryErr := apply()
+	if retryErr == nil && eventName == adaptors.EventUnknown.String() {
+		// Quarantined before the indexer skipped events that are not
+		// Pitchlake's, there is nothing to apply
+		log.Printf("Quarantined event %d is not a Pitchlake event, dropping it", q.ID)
+		r.result.dropped++
+		return r.tx.DeleteQuarantinedEvent(q.ID)
+	}
+	if retryErr == nil {
+		applied := q.Event()
+		applied.EventName = eventName
+		if err := r.tx.CreateEvent(&applied); err != nil {
+			return err
+		}
+		r.result.applied++
+		return r.tx.DeleteQuarantinedEvent(q.ID)
+	}
+	if err := r.tx.RollbackTo("retry"); err != nil {
+		return err
+	}
+	r.result.failed++
+	log.Printf("Quarantined event %d still fails: %v", q.ID, retryErr)
+	return r.tx.RecordQuarantineRetry(q.ID, retryErr.Error())
+}
+
+// countUntracked counts the quarantined events no tracked contract claimed
+// during the replay
+func (r *replayRetrier) countUntracked() {
+	for _, quarantined := range r.byBlock {
+		for _, q := range quarantined {
+			if !r.attempted[q.ID] {
+				log.Printf("Quarantined event %d: %s is no longer tracked, skipping", q.ID, q.FromAddress)
+				r.result.untracked++
+			}
+		}
+	}
+}
+
+func uniqueSorted(values []uint64) []uint64 {
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	unique := values[:0]
+	for _, value := range values {
+		if len(unique) == 0 || value != unique[len(unique)-1] {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"junoplugin/config"
+	"junoplugin/db"
+	"junoplugin/indexer"
+	"log"
+)
+
+// retryQuarantined runs the quarantined vault and round events through the
+// indexer again, typically after deploying a fix for the handler or ABI that
+// rejected them. The balances in the events are absolute, so an event cannot
+// be applied on top of newer state: instead the derived state is rebuilt
+// from the event log with the quarantined events merged in at their original
+// block, transaction and event index. Events that apply join the Events
+// table at those coordinates, so a later revert of their block drops them
+// with it; events that still fail keep their row with the new error. Events
+// quarantined by processUDC are left alone since the constructor events they
+// need are not stored. Everything runs in one transaction and the plugin
+// must be stopped while it runs.
+func retryQuarantined() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	database, err := db.Init(cfg.DBURL)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	idx, err := indexer.New(database, cfg, false)
+	if err != nil {
+		return err
+	}
+	quarantined, err := database.GetQuarantinedEvents(indexer.HandlerVault, indexer.HandlerRound)
+	if err != nil {
+		return err
+	}
+	if len(quarantined) == 0 {
+		log.Printf("No quarantined events to retry")
+		return nil
+	}
+	return database.WithBlockTx(func(tx *db.DB) error {
+		result, err := replay(tx, idx, cfg.JournalRetention, quarantined)
+		if err != nil {
+			return err
+		}
+		log.Printf(
+			"Retried %d quarantined events: %d applied, %d still failing, %d untracked, %d not Pitchlake events",
+			len(quarantined),
+			result.applied,
+			result.failed,
+			result.untracked,
+			result.dropped,
+		)
+		return nil
+	})
+}
@@ -0,0 +1,306 @@
+package config
+
+import (
+	"fmt"
+	"junoplugin/hexutil"
+	"junoplugin/versions"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PathEnv names the environment variable pointing at the optional YAML config file.
+const PathEnv = "PITCHLAKE_CONFIG"
+
+// Error policies applied when indexing a block fails
+const (
+	// ErrorPolicyHalt stops indexing until the failed block applies on a retry
+	ErrorPolicyHalt = "halt"
+	// ErrorPolicyRetry retries the whole block with exponential backoff, then halts
+	ErrorPolicyRetry = "retry"
+	// ErrorPolicySkip skips the failing event and quarantines it
+	ErrorPolicySkip = "skip"
+)
+
+type Config struct {
+	DBURL      string `yaml:"db_url"`
+	UDCAddress string `yaml:"udc_address"`
+	// VaultHash is the vault class decoded with versions.Default.
+	// VaultClasses and OptionRoundClasses map further class hashes to the
+	// contract version (see versions.All) their events are decoded with.
+	// Option rounds of unlisted classes use the version of their vault.
+	VaultHash          string            `yaml:"vault_hash"`
+	VaultClasses       map[string]string `yaml:"vault_classes"`
+	OptionRoundClasses map[string]string `yaml:"option_round_classes"`
+	Deployer           string            `yaml:"deployer"`
+	Cursor             uint64            `yaml:"cursor"`
+	// FeederURL is the Starknet feeder gateway used to replay blocks the
+	// plugin missed while detached. Without it, indexing stops at the first
+	// such gap.
+	FeederURL   string      `yaml:"feeder_url"`
+	ErrorPolicy ErrorPolicy `yaml:"error_policy"`
+	// JournalRetention is the number of recent blocks whose change journal is
+	// kept, i.e. the deepest reorg RevertBlock can undo.
+	JournalRetention uint64 `yaml:"journal_retention"`
+	API              API    `yaml:"api"`
+}
+
+// API configures the read-only HTTP query server started by the plugin. It
+// serves the REST, WebSocket, GraphQL and pitchlake_* JSON-RPC endpoints on
+// its own port, separate from Juno's RPC server; none of them are served
+// while it is disabled.
+type API struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+	// AllowedOrigins are the host patterns (e.g. "app.pitchlake.xyz",
+	// "*.pitchlake.xyz") browsers may open WebSocket connections from.
+	// Same-origin connections are always accepted.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type ErrorPolicy struct {
+	Mode          string        `yaml:"mode"`
+	RetryAttempts int           `yaml:"retry_attempts"`
+	RetryBackoff  time.Duration `yaml:"retry_backoff"`
+}
+
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+// ValidationError lists every invalid field so a misconfigured indexer
+// reports all of its problems at once.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("invalid pitchlake plugin configuration:")
+	for _, fieldErr := range e.Errors {
+		sb.WriteString(fmt.Sprintf("\n  %s: %s", fieldErr.Field, fieldErr.Reason))
+	}
+	return sb.String()
+}
+
+func (e *ValidationError) add(field, reason string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Reason: reason})
+}
+
+// Load reads the config file named by PITCHLAKE_CONFIG (if set), applies the
+// environment overrides and validates the result.
+func Load() (*Config, error) {
+	return LoadFile(os.Getenv(PathEnv))
+}
+
+func LoadFile(path string) (*Config, error) {
+	cfg := &Config{
+		JournalRetention: 1000,
+		API: API{
+			Port: 8090,
+		},
+		ErrorPolicy: ErrorPolicy{
+			Mode:          ErrorPolicyHalt,
+			RetryAttempts: 5,
+			RetryBackoff:  time.Second,
+		},
+	}
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file %s: %w", path, err)
+		}
+		if err := yaml.Unmarshal(raw, cfg); err != nil {
+			return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	}
+
+	validationErr := &ValidationError{}
+	cfg.applyEnv(validationErr)
+	cfg.validate(validationErr)
+	if len(validationErr.Errors) > 0 {
+		return nil, validationErr
+	}
+	return cfg, nil
+}
+
+func (c *Config) applyEnv(validationErr *ValidationError) {
+	overrideString(&c.DBURL, "DB_URL")
+	overrideString(&c.UDCAddress, "UDC_ADDRESS")
+	overrideString(&c.VaultHash, "VAULT_HASH")
+	overrideString(&c.Deployer, "DEPLOYER")
+	overrideString(&c.FeederURL, "FEEDER_URL")
+	overrideString(&c.ErrorPolicy.Mode, "ERROR_POLICY")
+	overrideClasses(validationErr, &c.VaultClasses, "vault_classes", "VAULT_CLASSES")
+	overrideClasses(validationErr, &c.OptionRoundClasses, "option_round_classes", "OPTION_ROUND_CLASSES")
+	if cursor, ok := os.LookupEnv("CURSOR"); ok && cursor != "" {
+		parsed, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			validationErr.add("cursor", fmt.Sprintf("CURSOR must be a block number, got %q", cursor))
+		} else {
+			c.Cursor = parsed
+		}
+	}
+	if retention, ok := os.LookupEnv("JOURNAL_RETENTION"); ok && retention != "" {
+		parsed, err := strconv.ParseUint(retention, 10, 64)
+		if err != nil {
+			validationErr.add("journal_retention", fmt.Sprintf("JOURNAL_RETENTION must be a number of blocks, got %q", retention))
+		} else {
+			c.JournalRetention = parsed
+		}
+	}
+	if attempts, ok := os.LookupEnv("RETRY_ATTEMPTS"); ok && attempts != "" {
+		parsed, err := strconv.Atoi(attempts)
+		if err != nil {
+			validationErr.add("error_policy.retry_attempts", fmt.Sprintf("RETRY_ATTEMPTS must be a number, got %q", attempts))
+		} else {
+			c.ErrorPolicy.RetryAttempts = parsed
+		}
+	}
+	if enabled, ok := os.LookupEnv("API_ENABLED"); ok && enabled != "" {
+		parsed, err := strconv.ParseBool(enabled)
+		if err != nil {
+			validationErr.add("api.enabled", fmt.Sprintf("API_ENABLED must be true or false, got %q", enabled))
+		} else {
+			c.API.Enabled = parsed
+		}
+	}
+	if port, ok := os.LookupEnv("API_PORT"); ok && port != "" {
+		parsed, err := strconv.Atoi(port)
+		if err != nil {
+			validationErr.add("api.port", fmt.Sprintf("API_PORT must be a port number, got %q", port))
+		} else {
+			c.API.Port = parsed
+		}
+	}
+	if origins, ok := os.LookupEnv("API_ALLOWED_ORIGINS"); ok && origins != "" {
+		c.API.AllowedOrigins = nil
+		for _, origin := range strings.Split(origins, ",") {
+			c.API.AllowedOrigins = append(c.API.AllowedOrigins, strings.TrimSpace(origin))
+		}
+	}
+	if backoff, ok := os.LookupEnv("RETRY_BACKOFF"); ok && backoff != "" {
+		parsed, err := time.ParseDuration(backoff)
+		if err != nil {
+			validationErr.add("error_policy.retry_backoff", fmt.Sprintf("RETRY_BACKOFF must be a duration, got %q", backoff))
+		} else {
+			c.ErrorPolicy.RetryBackoff = parsed
+		}
+	}
+}
+
+func (c *Config) validate(validationErr *ValidationError) {
+	if strings.TrimSpace(c.DBURL) == "" {
+		validationErr.add("db_url", "must not be empty")
+	}
+	c.UDCAddress = normalizeField(validationErr, "udc_address", c.UDCAddress)
+	if c.VaultHash != "" || len(c.VaultClasses) == 0 {
+		c.VaultHash = normalizeField(validationErr, "vault_hash", c.VaultHash)
+	}
+	c.VaultClasses = normalizeClasses(validationErr, "vault_classes", c.VaultClasses)
+	c.OptionRoundClasses = normalizeClasses(validationErr, "option_round_classes", c.OptionRoundClasses)
+	c.Deployer = normalizeField(validationErr, "deployer", c.Deployer)
+	if c.FeederURL != "" {
+		parsed, err := url.Parse(c.FeederURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			validationErr.add("feeder_url", fmt.Sprintf("must be an http(s) URL, got %q", c.FeederURL))
+		}
+	}
+	switch c.ErrorPolicy.Mode {
+	case ErrorPolicyHalt, ErrorPolicyRetry, ErrorPolicySkip:
+	default:
+		validationErr.add("error_policy.mode", fmt.Sprintf(
+			"must be one of %s, %s or %s, got %q",
+			ErrorPolicyHalt,
+			ErrorPolicyRetry,
+			ErrorPolicySkip,
+			c.ErrorPolicy.Mode,
+		))
+	}
+	if c.JournalRetention == 0 {
+		validationErr.add("journal_retention", "must be at least 1 block")
+	}
+	if c.ErrorPolicy.RetryAttempts < 1 {
+		validationErr.add("error_policy.retry_attempts", "must be at least 1")
+	}
+	if c.ErrorPolicy.RetryBackoff <= 0 {
+		validationErr.add("error_policy.retry_backoff", "must be positive")
+	}
+	if c.API.Enabled && (c.API.Port < 1 || c.API.Port > 65535) {
+		validationErr.add("api.port", fmt.Sprintf("must be between 1 and 65535, got %d", c.API.Port))
+	}
+}
+
+// VaultVersions maps every indexed vault class hash to its contract version
+func (c *Config) VaultVersions() map[string]string {
+	classVersions := make(map[string]string, len(c.VaultClasses)+1)
+	if c.VaultHash != "" {
+		classVersions[c.VaultHash] = versions.Default
+	}
+	for classHash, version := range c.VaultClasses {
+		classVersions[classHash] = version
+	}
+	return classVersions
+}
+
+func overrideString(field *string, env string) {
+	if value, ok := os.LookupEnv(env); ok && value != "" {
+		*field = value
+	}
+}
+
+func normalizeField(validationErr *ValidationError, field, value string) string {
+	if strings.TrimSpace(value) == "" {
+		validationErr.add(field, "must not be empty")
+		return value
+	}
+	normalized, err := hexutil.NormalizeHexString(value)
+	if err != nil {
+		validationErr.add(field, err.Error())
+		return value
+	}
+	return normalized
+}
+
+// overrideClasses replaces classes with a comma separated list of
+// class_hash=version pairs
+func overrideClasses(validationErr *ValidationError, classes *map[string]string, field, env string) {
+	value, ok := os.LookupEnv(env)
+	if !ok || value == "" {
+		return
+	}
+	*classes = make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		classHash, version, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			validationErr.add(field, fmt.Sprintf("%s entries must be class_hash=version, got %q", env, pair))
+			continue
+		}
+		(*classes)[strings.TrimSpace(classHash)] = strings.TrimSpace(version)
+	}
+}
+
+func normalizeClasses(validationErr *ValidationError, field string, classes map[string]string) map[string]string {
+	if len(classes) == 0 {
+		return classes
+	}
+	normalized := make(map[string]string, len(classes))
+	for classHash, version := range classes {
+		key := normalizeField(validationErr, field, classHash)
+		if !versions.Known(version) {
+			validationErr.add(field, fmt.Sprintf(
+				"class %s: unknown contract version %q, expected one of %s",
+				classHash,
+				version,
+				strings.Join(versions.All(), ", "),
+			))
+		}
+		normalized[key] = version
+	}
+	return normalized
+}
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envVars are every variable Load reads besides PITCHLAKE_CONFIG
+var envVars = []string{
+	"DB_URL", "UDC_ADDRESS", "VAULT_HASH", "DEPLOYER", "FEEDER_URL", "ERROR_POLICY",
+	"VAULT_CLASSES", "OPTION_ROUND_CLASSES", "CURSOR", "JOURNAL_RETENTION",
+	"RETRY_ATTEMPTS", "RETRY_BACKOFF", "API_ENABLED", "API_PORT", "API_ALLOWED_ORIGINS",
+}
+
+const validYAML = `
+db_url: postgres://pitchlake@localhost/pitchlake
+udc_address: "0x041A78E741E5AF2FEC34B695679BC6891742439F7AFB8484ECD7766661AD02BF"
+vault_hash: "0x00000abc"
+deployer: "0xDEF"
+cursor: 120
+`
+
+// loadWith clears the environment overrides, writes yaml to a temporary
+// config file and loads it with env set on top
+func loadWith(t *testing.T, yaml string, env map[string]string) (*Config, error) {
+	t.Helper()
+	for _, name := range envVars {
+		t.Setenv(name, "")
+	}
+	path := filepath.Join(t.TempDir(), "pitchlake.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(PathEnv, path)
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+	return Load()
+}
+
+// fieldErrors returns the fields listed by a *ValidationError
+func fieldErrors(t *testing.T, err error) []string {
+	t.Helper()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Load error = %v, want a *ValidationError", err)
+	}
+	fields := make([]string, 0, len(validationErr.Errors))
+	for _, fieldErr := range validationErr.Errors {
+		fields = append(fields, fieldErr.Field)
+	}
+	return fields
+}
+
+func TestLoadFile(t *testing.T) {
+	cfg, err := loadWith(t, validYAML, nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.UDCAddress != "0x41a78e741e5af2fec34b695679bc6891742439f7afb8484ecd7766661ad02bf" {
+		t.Errorf("UDCAddress = %s, want it lowercased without leading zeros", cfg.UDCAddress)
+	}
+	if cfg.VaultHash != "0xabc" {
+		t.Errorf("VaultHash = %s, want 0xabc", cfg.VaultHash)
+	}
+	if cfg.Deployer != "0xdef" {
+		t.Errorf("Deployer = %s, want 0xdef", cfg.Deployer)
+	}
+	if cfg.Cursor != 120 {
+		t.Errorf("Cursor = %d, want 120", cfg.Cursor)
+	}
+	// Defaults for everything the file leaves out
+	if cfg.ErrorPolicy.Mode != ErrorPolicyHalt || cfg.ErrorPolicy.RetryAttempts != 5 || cfg.ErrorPolicy.RetryBackoff != time.Second {
+		t.Errorf("ErrorPolicy = %+v, want the halt defaults", cfg.ErrorPolicy)
+	}
+	if cfg.JournalRetention != 1000 || cfg.API.Port != 8090 || cfg.API.Enabled {
+		t.Errorf("JournalRetention = %d, API = %+v, want the defaults", cfg.JournalRetention, cfg.API)
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	cfg, err := loadWith(t, validYAML, map[string]string{
+		"DB_URL":               "postgres://other@db/pitchlake",
+		"DEPLOYER":             "0x0FF",
+		"CURSOR":               "500",
+		"ERROR_POLICY":         ErrorPolicySkip,
+		"RETRY_BACKOFF":        "250ms",
+		"API_ENABLED":          "true",
+		"API_PORT":             "9000",
+		"API_ALLOWED_ORIGINS":  "app.pitchlake.xyz, *.pitchlake.xyz",
+		"VAULT_CLASSES":        "0x0B=v2",
+		"OPTION_ROUND_CLASSES": "0xC=v1,0xd=v2",
+	})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DBURL != "postgres://other@db/pitchlake" {
+		t.Errorf("DBURL = %s, want the DB_URL override", cfg.DBURL)
+	}
+	if cfg.Deployer != "0xff" {
+		t.Errorf("Deployer = %s, want the normalized DEPLOYER override", cfg.Deployer)
+	}
+	if cfg.Cursor != 500 {
+		t.Errorf("Cursor = %d, want the CURSOR override", cfg.Cursor)
+	}
+	if cfg.ErrorPolicy.Mode != ErrorPolicySkip || cfg.ErrorPolicy.RetryBackoff != 250*time.Millisecond {
+		t.Errorf("ErrorPolicy = %+v, want the ERROR_POLICY and RETRY_BACKOFF overrides", cfg.ErrorPolicy)
+	}
+	if !cfg.API.Enabled || cfg.API.Port != 9000 {
+		t.Errorf("API = %+v, want it enabled on 9000", cfg.API)
+	}
+	if strings.Join(cfg.API.AllowedOrigins, "|") != "app.pitchlake.xyz|*.pitchlake.xyz" {
+		t.Errorf("AllowedOrigins = %q", cfg.API.AllowedOrigins)
+	}
+	if cfg.VaultClasses["0xb"] != "v2" || len(cfg.VaultClasses) != 1 {
+		t.Errorf("VaultClasses = %v, want 0xb=v2", cfg.VaultClasses)
+	}
+	if cfg.OptionRoundClasses["0xc"] != "v1" || cfg.OptionRoundClasses["0xd"] != "v2" {
+		t.Errorf("OptionRoundClasses = %v, want 0xc=v1 and 0xd=v2", cfg.OptionRoundClasses)
+	}
+	versions := cfg.VaultVersions()
+	if versions["0xabc"] != "v1" || versions["0xb"] != "v2" {
+		t.Errorf("VaultVersions = %v, want the vault_hash on v1 and 0xb on v2", versions)
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	for _, name := range envVars {
+		t.Setenv(name, "")
+	}
+	t.Setenv(PathEnv, "")
+	t.Setenv("DB_URL", "postgres://localhost/pitchlake")
+	t.Setenv("UDC_ADDRESS", "0x1")
+	t.Setenv("VAULT_HASH", "0x2")
+	t.Setenv("DEPLOYER", "0x3")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DBURL != "postgres://localhost/pitchlake" || cfg.VaultHash != "0x2" {
+		t.Errorf("Load = %+v, want the environment values", cfg)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		yaml   string
+		env    map[string]string
+		fields []string
+	}{
+		{
+			name:   "blank DSN in the file",
+			yaml:   strings.Replace(validYAML, "postgres://pitchlake@localhost/pitchlake", `"  "`, 1),
+			fields: []string{"db_url"},
+		},
+		{
+			name:   "non-numeric CURSOR",
+			yaml:   validYAML,
+			env:    map[string]string{"CURSOR": "12abc"},
+			fields: []string{"cursor"},
+		},
+		{
+			name:   "negative CURSOR",
+			yaml:   validYAML,
+			env:    map[string]string{"CURSOR": "-1"},
+			fields: []string{"cursor"},
+		},
+		{
+			name:   "invalid hex address",
+			yaml:   strings.Replace(validYAML, `"0xDEF"`, `"0xNOTHEX"`, 1),
+			fields: []string{"deployer"},
+		},
+		{
+			name:   "unknown contract version",
+			yaml:   validYAML,
+			env:    map[string]string{"VAULT_CLASSES": "0xb=v9"},
+			fields: []string{"vault_classes"},
+		},
+		{
+			name:   "malformed class list",
+			yaml:   validYAML,
+			env:    map[string]string{"OPTION_ROUND_CLASSES": "0xc"},
+			fields: []string{"option_round_classes"},
+		},
+		{
+			name: "every invalid field at once",
+			yaml: `
+feeder_url: "ftp://feeder"
+error_policy:
+  mode: explode
+  retry_attempts: 0
+journal_retention: 0
+api:
+  enabled: true
+  port: 70000
+`,
+			env: map[string]string{"RETRY_BACKOFF": "soon"},
+			fields: []string{
+				"error_policy.retry_backoff",
+				"db_url",
+				"udc_address",
+				"vault_hash",
+				"deployer",
+				"feeder_url",
+				"error_policy.mode",
+				"journal_retention",
+				"error_policy.retry_attempts",
+				"api.port",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadWith(t, tt.yaml, tt.env)
+			if got, want := strings.Join(fieldErrors(t, err), ", "), strings.Join(tt.fields, ", "); got != want {
+				t.Errorf("invalid fields = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorListsEveryField(t *testing.T) {
+	_, err := loadWith(t, "", nil)
+	if err == nil {
+		t.Fatal("Load of an empty config succeeded")
+	}
+	message := err.Error()
+	for _, field := range []string{"db_url", "udc_address", "vault_hash", "deployer"} {
+		if !strings.Contains(message, "\n  "+field+": must not be empty") {
+			t.Errorf("error %q does not list %s", message, field)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"errors"
+	"junoplugin/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const checkpointID = 1
+
+// GetCheckpoint returns the last indexed block, or nil if nothing has been indexed yet
+func (db *DB) GetCheckpoint() (*models.IndexerCheckpoint, error) {
+	var checkpoint models.IndexerCheckpoint
+	if err := db.Conn.Where("id = ?", checkpointID).First(&checkpoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// UpsertCheckpoint must be called inside the block transaction so the
+// checkpoint only advances together with the block's writes
+func (db *DB) UpsertCheckpoint(blockNumber uint64, blockHash string) error {
+	checkpoint := models.IndexerCheckpoint{
+		ID:          checkpointID,
+		BlockNumber: blockNumber,
+		BlockHash:   blockHash,
+	}
+	return db.tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"block_number", "block_hash"}),
+	}).Create(&checkpoint).Error
+}
+
+func (db *DB) DeleteCheckpoint() error {
+	return db.tx.Where("id = ?", checkpointID).Delete(&models.IndexerCheckpoint{}).Error
+}
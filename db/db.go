@@ -2,6 +2,7 @@ package db
 
 import (
 	"errors"
+	"fmt"
 	"junoplugin/models"
 	"log"
 	"math/big"
@@ -58,6 +59,27 @@ func Init(dsn string) (*DB, error) {
 	return &DB{Conn: conn}, nil
 }
 
+// Open connects to a database that Init has already migrated. The API, GraphQL
+// and RPC servers read through their own DB from Open so their request
+// goroutines never touch the indexer's block transaction.
+func Open(dsn string) (*DB, error) {
+	conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		return nil, err
+	}
+	return &DB{Conn: conn}, nil
+}
+
+// reader returns the open block transaction when there is one, so reads made
+// while indexing a block see that block's earlier writes. It is for the
+// indexer only: tx is not synchronized, so request handlers must read Conn.
+func (db *DB) reader() *gorm.DB {
+	if db.tx != nil {
+		return db.tx
+	}
+	return db.Conn
+}
+
 func (db *DB) Close() error {
 	//Close the DB connection
 	sqlDB, err := db.Conn.DB()
@@ -298,6 +320,18 @@ func (db *DB) GetVaultAddresses() ([]string, error) {
 	return addresses, nil
 }
 
+// GetVaultClasses returns every vault with only its address and the class
+// hashes of the vault and its option rounds set
+func (db *DB) GetVaultClasses() ([]models.VaultState, error) {
+	var vaults []models.VaultState
+	if err := db.Conn.Model(&models.VaultState{}).
+		Select("address", "class_hash", "option_round_class_hash").
+		Find(&vaults).Error; err != nil {
+		return nil, err
+	}
+	return vaults, nil
+}
+
 func (db *DB) GetRoundAddressess(vaultAddress string) (*[]string, error) {
 	var addresses []string
 
@@ -359,6 +393,33 @@ func (db *DB) UpsertLiquidityProviderState(lp *models.LiquidityProviderState, bl
 
 }
 
+func (db *DB) UpsertRoundMetrics(roundMetrics *models.RoundMetrics) error {
+	return db.tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "round_address"}},
+		UpdateAll: true,
+	}).Create(roundMetrics).Error
+}
+
+func (db *DB) CreateLPAction(action *models.LPAction) error {
+	return db.tx.Create(action).Error
+}
+
+func (db *DB) UpsertL1Request(request *models.L1Request) error {
+	return db.tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "vault_address"}, {Name: "round_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"request_id",
+			"caller",
+			"fossil_client_address",
+			"strike_price",
+			"cap_level",
+			"reserve_price",
+			"fulfilled_block",
+			"fulfilled_at",
+		}),
+	}).Create(request).Error
+}
+
 func (db *DB) UpdateOptionBuyerFields(
 	address string,
 	roundAddress string,
@@ -421,7 +482,7 @@ func (db *DB) DeleteBid(bidID string, roundAddress string) error {
 }
 func (db *DB) GetBidsForRound(roundAddress string) ([]models.Bid, error) {
 	var bids []models.Bid
-	if err := db.Conn.Where("round_address = ?", roundAddress).Order("price DESC,tree_nonce ASC").Find(&bids).Error; err != nil {
+	if err := db.reader().Where("round_address = ?", roundAddress).Order("price DESC,tree_nonce ASC").Find(&bids).Error; err != nil {
 		return nil, err
 	}
 	return bids, nil
@@ -433,7 +494,7 @@ func (db *DB) GetBidsAboveClearingForRound(
 	clearingNonce uint64,
 ) ([]models.Bid, error) {
 	var bids []models.Bid
-	if err := db.Conn.
+	if err := db.reader().
 		Where("round_address = ?", roundAddress).
 		Where("price > ? OR (price = ? AND tree_nonce <= ?)", clearingPrice, clearingPrice, clearingNonce).
 		Order("price DESC, tree_nonce ASC").
@@ -451,7 +512,7 @@ func (db *DB) GetBidsBelowClearingForRound(
 ) ([]models.Bid, error) {
 	var bids []models.Bid
 
-	if err := db.Conn.Where("round_address = ?", roundAddress).
+	if err := db.reader().Where("round_address = ?", roundAddress).
 		Where("price < ? OR ( price = ? AND tree_nonce >?) ", clearingPrice, clearingPrice, clearingNonce).
 		Find(&bids).Error; err != nil {
 		return nil, err
@@ -463,133 +524,98 @@ func (db *DB) GetBidsBelowClearingForRound(
 func (db *DB) GetAllQueuedLiquidityForRound(roundAddress string) ([]models.QueuedLiquidity, error) {
 
 	var queuedAmounts []models.QueuedLiquidity
-	if err := db.Conn.Where("round_address=?", roundAddress).Find(&queuedAmounts).Error; err != nil {
+	if err := db.reader().Where("round_address=?", roundAddress).Find(&queuedAmounts).Error; err != nil {
 		return nil, err
 	}
 	return queuedAmounts, nil
 }
 
-// Revert Functions
-func (db *DB) RevertVaultState(address string, blockNumber uint64) error {
-	var vaultState models.VaultState
-	var vaultHistoric, postRevert models.Vault
-	if err := db.tx.Where("address = ? AND last_block = ?", address, blockNumber).First(&vaultState).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil
-		} else {
-			return err
-		}
-	}
-
-	if err := db.tx.Where("address = ? AND block_number = ?", address, blockNumber).First(&vaultHistoric).Error; err != nil {
-		return err
-	}
-
-	if err := db.tx.Delete(&vaultHistoric).Error; err != nil {
-		return err
+// TruncateDerivedTables empties every table derived from the raw event log,
+// including the historic snapshots written by the logging triggers
+func (db *DB) TruncateDerivedTables() error {
+	return db.tx.Exec(`TRUNCATE TABLE
+		"VaultStates",
+		"Vault_Historic",
+		"Liquidity_Providers",
+		"Liquidity_Providers_Historic",
+		"Option_Rounds",
+		"Option_Rounds_Historic",
+		"Option_Buyers",
+		"Option_Buyers_Historic",
+		"Bids",
+		"Bids_Historic",
+		"Queued_Liquidity",
+		"Queued_Liquidity_Historic",
+		"Option_Holders",
+		"Option_Holders_Historic",
+		"L1_Requests",
+		"LP_Actions",
+		"Round_Metrics",
+		"Block_Journal"`).Error
+}
+
+func (db *DB) Begin() error {
+	if db.tx != nil {
+		return errors.New("a transaction is already open")
 	}
-
-	if err := db.tx.Where("address = ?", address).
-		Order("latest_block DESC").
-		First(&postRevert).Error; err != nil {
-		return nil
-	}
-
-	if err := db.tx.Where("address = ?").Updates(map[string]interface{}{
-		"unlocked_balance": postRevert.UnlockedBalance,
-		"locked_balance":   postRevert.LockedBalance,
-		"stashed_balance":  postRevert.StashedBalance,
-		"latest_block":     postRevert.BlockNumber,
-	}).Error; err != nil {
-		return err
+	tx := db.Conn.Begin()
+	if tx.Error != nil {
+		return tx.Error
 	}
-
+	db.tx = tx
 	return nil
 }
 
-func (db *DB) RevertAllLPState(vaultAddress string, blockNumber uint64) error {
-	var lpStates []models.LiquidityProviderState
-	var lpHistoric, postRevert models.LiquidityProvider
-	if err := db.tx.Model(models.LiquidityProviderState{}).Where("vault_address = ? AND last_block = ?", vaultAddress, blockNumber).Find(&lpStates).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil
-		} else {
-			return err
-		}
+func (db *DB) Commit() error {
+	if db.tx == nil {
+		return errors.New("no open transaction to commit")
 	}
-
-	for _, lpState := range lpStates {
-		if err := db.tx.Model(models.LiquidityProvider{}).Where("vault_address = ? AND address = ? AND block_number = ?", vaultAddress, lpState.Address, blockNumber).First(&lpHistoric).Error; err != nil {
-			return err
-		}
-
-		if err := db.tx.Delete(&lpHistoric).Error; err != nil {
-			return err
-		}
-
-		if err := db.tx.Where("vault_address = ? AND address = ?", vaultAddress, lpState.Address).
-			Order("latest_block DESC").
-			First(&postRevert).Error; err != nil {
-			return nil
-		}
-
-		if err := db.tx.Where("vault_address = ? AND address = ?").Updates(map[string]interface{}{
-			"unlocked_balance": postRevert.UnlockedBalance,
-			"locked_balance":   postRevert.LockedBalance,
-			"stashed_balance":  postRevert.StashedBalance,
-			"latest_block":     postRevert.BlockNumber,
-		}).Error; err != nil {
-			return err
-		}
-	}
-	return nil
+	err := db.tx.Commit().Error
+	db.tx = nil
+	return err
 }
 
-func (db *DB) RevertLPState(vaultAddress, address string, blockNumber uint64) error {
-	var lpState models.LiquidityProviderState
-	var lpHistoric, postRevert models.LiquidityProvider
-	if err := db.tx.Where("vault_address = ? AND address = ? AND last_block = ?", vaultAddress, address, blockNumber).First(&lpState).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil
-		} else {
-			return err
-		}
+func (db *DB) Rollback() error {
+	if db.tx == nil {
+		return nil
 	}
+	err := db.tx.Rollback().Error
+	db.tx = nil
+	return err
+}
 
-	if err := db.tx.Model(models.LiquidityProvider{}).Where("vault_address = ? AND address = ? AND block_number = ?", vaultAddress, address, blockNumber).First(&lpHistoric).Error; err != nil {
+// WithBlockTx runs fn inside a transaction that is committed when fn succeeds and
+// rolled back otherwise, so a block's writes are applied entirely or not at all
+func (db *DB) WithBlockTx(fn func(tx *DB) error) error {
+	if err := db.Begin(); err != nil {
 		return err
 	}
-
-	if err := db.tx.Delete(&lpHistoric).Error; err != nil {
+	defer func() {
+		if r := recover(); r != nil {
+			db.Rollback()
+			panic(r)
+		}
+	}()
+	if err := fn(db); err != nil {
+		if rollbackErr := db.Rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
 		return err
 	}
-
-	if err := db.tx.Model(models.LiquidityProvider{}).Where("vault_address = ? AND address = ?", vaultAddress, address).
-		Order("latest_block DESC").
-		First(&postRevert).Error; err != nil {
-		return nil
-	}
-
-	if err := db.tx.Model(models.LiquidityProviderState{}).Where("vault_address = ? AND address = ?", vaultAddress, address).Updates(map[string]interface{}{
-		"unlocked_balance": postRevert.UnlockedBalance,
-		"locked_balance":   postRevert.LockedBalance,
-		"stashed_balance":  postRevert.StashedBalance,
-		"latest_block":     postRevert.BlockNumber,
-	}).Error; err != nil {
-		return err
+	if err := db.Commit(); err != nil {
+		return fmt.Errorf("committing block transaction: %w", err)
 	}
-
 	return nil
 }
 
-func (db *DB) Begin() {
-	tx := db.Conn.Begin()
-	db.tx = tx
+// SavePoint and RollbackTo let a single event be undone without discarding
+// the rest of the block transaction
+func (db *DB) SavePoint(name string) error {
+	return db.tx.SavePoint(name).Error
 }
 
-func (db *DB) Commit() {
-	db.tx.Commit()
-	db.tx = nil
+func (db *DB) RollbackTo(name string) error {
+	return db.tx.RollbackTo(name).Error
 }
 
 func (db *DB) Tx(tx *gorm.DB) {
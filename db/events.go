@@ -0,0 +1,71 @@
+package db
+
+import (
+	"junoplugin/models"
+
+	"gorm.io/gorm"
+)
+
+func (db *DB) CreateEvent(event *models.Event) error {
+	return db.tx.Create(event).Error
+}
+
+// GetEventsForBlock returns the events applied for a block in the order they were emitted
+func (db *DB) GetEventsForBlock(blockNumber uint64) ([]models.Event, error) {
+	var events []models.Event
+	if err := db.tx.Where("block_number = ?", blockNumber).
+		Order("transaction_index ASC, event_index ASC").
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (db *DB) DeleteEventsForBlock(blockNumber uint64) error {
+	return db.tx.Where("block_number = ?", blockNumber).Delete(&models.Event{}).Error
+}
+
+// GetEventBlockNumbers lists every block with recorded events in ascending order
+func (db *DB) GetEventBlockNumbers() ([]uint64, error) {
+	var blockNumbers []uint64
+	if err := db.tx.Model(&models.Event{}).
+		Distinct("block_number").
+		Order("block_number ASC").
+		Pluck("block_number", &blockNumbers).Error; err != nil {
+		return nil, err
+	}
+	return blockNumbers, nil
+}
+
+func (db *DB) CreateQuarantinedEvent(event *models.QuarantinedEvent) error {
+	return db.tx.Create(event).Error
+}
+
+// DeleteQuarantinedEventsForBlock drops the dead letters of a reverted block
+func (db *DB) DeleteQuarantinedEventsForBlock(blockNumber uint64) error {
+	return db.tx.Where("block_number = ?", blockNumber).Delete(&models.QuarantinedEvent{}).Error
+}
+
+// GetQuarantinedEvents returns the quarantined events of the given handlers
+// in the order they were emitted
+func (db *DB) GetQuarantinedEvents(handlers ...string) ([]models.QuarantinedEvent, error) {
+	var events []models.QuarantinedEvent
+	if err := db.Conn.Where("handler IN ?", handlers).
+		Order("block_number ASC, transaction_index ASC, event_index ASC").
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (db *DB) DeleteQuarantinedEvent(id uint64) error {
+	return db.tx.Delete(&models.QuarantinedEvent{}, id).Error
+}
+
+// RecordQuarantineRetry stores the error of a failed retry
+func (db *DB) RecordQuarantineRetry(id uint64, retryErr string) error {
+	return db.tx.Model(&models.QuarantinedEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"error":   retryErr,
+		"retries": gorm.Expr("retries + 1"),
+	}).Error
+}
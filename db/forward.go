@@ -1,13 +1,18 @@
 package db
 
 import (
+	"fmt"
 	"junoplugin/models"
 	"log"
 	"math/big"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// zeroAddress is the sender of minted tokens and the recipient of burnt ones
+const zeroAddress = "0x0"
+
 func (db *DB) DepositIndex(
 	vaultAddress,
 	lpAddress string,
@@ -113,6 +118,27 @@ func (db *DB) RoundDeployedIndex(optionRound models.OptionRound) error {
 	return nil
 }
 
+// L1RequestFulfilledIndex links the fulfilled request to the vault's Fossil
+// client. A later fulfilment for the same round replaces the earlier one.
+func (db *DB) L1RequestFulfilledIndex(request models.L1Request) error {
+	vault, err := db.GetVaultByAddress(request.VaultAddress)
+	if err != nil {
+		return err
+	}
+	request.FossilClientAddress = vault.FossilClientAddress
+	if request.Caller != vault.FossilClientAddress {
+		log.Printf(
+			"L1 request %s for vault %s round %s fulfilled by %s, not the vault's fossil client %s",
+			request.RequestID,
+			request.VaultAddress,
+			request.RoundID.String(),
+			request.Caller,
+			vault.FossilClientAddress,
+		)
+	}
+	return db.UpsertL1Request(&request)
+}
+
 func (dbc *DB) PricingDataSetIndex(
 	roundAddress string,
 	strikePrice, capLevel, reservePrice models.BigInt) error {
@@ -219,6 +245,10 @@ func (db *DB) RoundSettledIndex(prevStateOptionRound models.OptionRound, roundAd
 		return err
 	}
 
+	if err := db.SettleOptionHolders(roundAddress, payoutPerOption); err != nil {
+		return err
+	}
+
 	if err := db.UpdateOptionRoundFields(prevStateOptionRound.Address, map[string]interface{}{
 		"settlement_price":    settlementPrice,
 		"payout_per_option":   payoutPerOption,
@@ -250,3 +280,128 @@ func (db *DB) BidUpdatedIndex(roundAddress, bidId string, price models.BigInt, t
 	}
 	return nil
 }
+
+// OptionsMintedIndex records the options a buyer minted as tokens, on the
+// buyer and in the round's total
+func (db *DB) OptionsMintedIndex(roundAddress, buyerAddress string, mintedAmount models.BigInt) error {
+	if err := db.UpdateOptionBuyerFields(buyerAddress, roundAddress, map[string]interface{}{
+		"has_minted":     true,
+		"minted_options": gorm.Expr("minted_options+?", mintedAmount),
+	}); err != nil {
+		return err
+	}
+	return db.UpdateOptionRoundFields(roundAddress, map[string]interface{}{
+		"minted_options": gorm.Expr("minted_options+?", mintedAmount),
+	})
+}
+
+// OptionsExercisedIndex records the options exercised by an account and
+// their payout. Token holders that never bid get an Option_Buyers row so
+// their payout is kept.
+func (db *DB) OptionsExercisedIndex(roundAddress, buyerAddress string, exercisedOptions, exercisedAmount models.BigInt) error {
+	if err := db.CreateOptionBuyer(&models.OptionBuyer{
+		Address:          buyerAddress,
+		RoundAddress:     roundAddress,
+		MintableOptions:  *models.NewBigInt("0"),
+		RefundableAmount: *models.NewBigInt("0"),
+		ExercisedOptions: *models.NewBigInt("0"),
+		ExercisedAmount:  *models.NewBigInt("0"),
+		MintedOptions:    *models.NewBigInt("0"),
+	}); err != nil {
+		return err
+	}
+	if err := db.UpdateOptionBuyerFields(buyerAddress, roundAddress, map[string]interface{}{
+		"has_minted":        true,
+		"exercised_options": gorm.Expr("exercised_options+?", exercisedOptions),
+		"exercised_amount":  gorm.Expr("exercised_amount+?", exercisedAmount),
+	}); err != nil {
+		return err
+	}
+	return db.UpdateOptionRoundFields(roundAddress, map[string]interface{}{
+		"exercised_options": gorm.Expr("exercised_options+?", exercisedOptions),
+		"exercised_amount":  gorm.Expr("exercised_amount+?", exercisedAmount),
+	})
+}
+
+// UnusedBidsRefundedIndex records the refund paid to a buyer and flags it
+// when it differs from the refundable amount indexed at the auction's end
+func (db *DB) UnusedBidsRefundedIndex(roundAddress, buyerAddress string, refundedAmount models.BigInt, blockNumber uint64) error {
+	if err := db.UpdateOptionBuyerFields(buyerAddress, roundAddress, map[string]interface{}{
+		"has_refunded":    true,
+		"refunded_amount": gorm.Expr("refunded_amount+?", refundedAmount),
+		"refunded_block":  blockNumber,
+		"refund_mismatch": gorm.Expr("refunded_amount+? <> COALESCE(refundable_amount, 0)", refundedAmount),
+	}); err != nil {
+		return err
+	}
+	var buyer models.OptionBuyer
+	if err := db.tx.Where("address = ? AND round_address = ?", buyerAddress, roundAddress).First(&buyer).Error; err != nil {
+		return err
+	}
+	if buyer.RefundMismatch {
+		log.Printf(
+			"Refund mismatch for buyer %s in round %s: refunded %s, indexed refundable amount %s",
+			buyerAddress,
+			roundAddress,
+			buyer.RefundedAmount.String(),
+			buyer.RefundableAmount.String(),
+		)
+	}
+	return nil
+}
+
+// TransferIndex moves option tokens between holders of the round. The zero
+// address is the minting or burning side; burns after settlement are
+// exercises and count towards the holder's exercised options.
+func (db *DB) TransferIndex(round models.OptionRound, from, to string, value models.BigInt, blockNumber uint64) error {
+	if from != zeroAddress {
+		updates := map[string]interface{}{
+			"balance":      gorm.Expr("balance-?", value),
+			"latest_block": blockNumber,
+		}
+		if to == zeroAddress && round.State == "Settled" {
+			updates["exercised_options"] = gorm.Expr("exercised_options+?", value)
+		}
+		result := db.tx.Model(models.OptionHolder{}).
+			Where("round_address = ? AND address = ?", round.Address, from).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("transfer from %s, which holds no options of round %s", from, round.Address)
+		}
+	}
+	if to != zeroAddress {
+		holder := models.OptionHolder{
+			RoundAddress:     round.Address,
+			Address:          to,
+			Balance:          value,
+			SettledBalance:   *models.NewBigInt("0"),
+			Payout:           *models.NewBigInt("0"),
+			ExercisedOptions: *models.NewBigInt("0"),
+			LatestBlock:      blockNumber,
+		}
+		if err := db.tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "round_address"}, {Name: "address"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "balance"}, Value: gorm.Expr(`"Option_Holders".balance+EXCLUDED.balance`)},
+				{Column: clause.Column{Name: "latest_block"}, Value: gorm.Expr("EXCLUDED.latest_block")},
+			},
+		}).Create(&holder).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SettleOptionHolders fixes every holder's balance at settlement and the
+// payout it is owed
+func (db *DB) SettleOptionHolders(roundAddress string, payoutPerOption models.BigInt) error {
+	return db.tx.Model(models.OptionHolder{}).
+		Where("round_address = ?", roundAddress).
+		Updates(map[string]interface{}{
+			"settled_balance": gorm.Expr("balance"),
+			"payout":          gorm.Expr("balance*?", payoutPerOption),
+		}).Error
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"errors"
+	"junoplugin/models"
+
+	"gorm.io/gorm"
+)
+
+// GetVaultStateAt returns the vault's balances at the end of blockNumber, or
+// nil if the vault had not been deployed by then. Served by the API as
+// GET /vaults/{vault}/state?block=N.
+func (db *DB) GetVaultStateAt(address string, blockNumber uint64) (*models.Vault, error) {
+	var vault models.Vault
+	if err := db.Conn.
+		Where("address = ? AND block_number <= ?", address, blockNumber).
+		Order("block_number DESC").
+		First(&vault).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &vault, nil
+}
+
+// GetLPStateAt returns the LP's balances in the vault at the end of
+// blockNumber, or nil if the LP had not deposited by then. Served by the API
+// as GET /vaults/{vault}/lps/{lp}/state?block=N.
+func (db *DB) GetLPStateAt(vaultAddress, lpAddress string, blockNumber uint64) (*models.LiquidityProvider, error) {
+	var lp models.LiquidityProvider
+	if err := db.Conn.
+		Where("vault_address = ? AND address = ? AND block_number <= ?", vaultAddress, lpAddress, blockNumber).
+		Order("block_number DESC").
+		First(&lp).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &lp, nil
+}
+
+// GetOptionRoundAt returns the round as it was at the end of blockNumber, or
+// nil if the round had not been deployed by then
+func (db *DB) GetOptionRoundAt(address string, blockNumber uint64) (*models.OptionRound, error) {
+	var round models.OptionRound
+	if err := db.Conn.
+		Table("Option_Rounds_Historic").
+		Where("address = ? AND block_number <= ?", address, blockNumber).
+		Order("block_number DESC").
+		First(&round).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &round, nil
+}
+
+// GetOptionHolderAt returns the holder's option balance at the end of
+// blockNumber, or nil if it had not held any of the round's options by then
+func (db *DB) GetOptionHolderAt(roundAddress, address string, blockNumber uint64) (*models.OptionHolder, error) {
+	var holder models.OptionHolder
+	if err := db.Conn.
+		Table("Option_Holders_Historic").
+		Where("round_address = ? AND address = ? AND block_number <= ?", roundAddress, address, blockNumber).
+		Order("block_number DESC").
+		First(&holder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &holder, nil
+}
+
+// GetVaultStateHistory returns a page of the vault's balances over
+// [fromBlock, toBlock] in block order, one entry per block that changed them.
+// The first entry is the state in effect at fromBlock, so the series can be
+// charted as a step function without a separate lookup. Served by the API as
+// GET /vaults/{vault}/history?from=N&to=M.
+func (db *DB) GetVaultStateHistory(address string, fromBlock, toBlock uint64, page Page) ([]models.Vault, error) {
+	start, err := db.GetVaultStateAt(address, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	return pageSeries(start, page, db.Conn.
+		Where("address = ? AND block_number > ? AND block_number <= ?", address, fromBlock, toBlock).
+		Order("block_number ASC"))
+}
+
+// GetLPStateHistory returns a page of the LP's balances in the vault over
+// [fromBlock, toBlock], seeded with the state in effect at fromBlock. Served
+// by the API as GET /vaults/{vault}/lps/{lp}/history?from=N&to=M.
+func (db *DB) GetLPStateHistory(vaultAddress, lpAddress string, fromBlock, toBlock uint64, page Page) ([]models.LiquidityProvider, error) {
+	start, err := db.GetLPStateAt(vaultAddress, lpAddress, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	return pageSeries(start, page, db.Conn.
+		Where("vault_address = ? AND address = ? AND block_number > ? AND block_number <= ?", vaultAddress, lpAddress, fromBlock, toBlock).
+		Order("block_number ASC"))
+}
+
+// pageSeries pages a history whose first entry is start, when there is one,
+// followed by the rows of rangeQuery
+func pageSeries[T any](start *T, page Page, rangeQuery *gorm.DB) ([]T, error) {
+	var series []T
+	if start != nil {
+		if page.Offset == 0 {
+			series = append(series, *start)
+			page.Limit--
+		} else {
+			page.Offset--
+		}
+	}
+	if page.Limit == 0 {
+		return series, nil
+	}
+	var rows []T
+	if err := page.apply(rangeQuery).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return append(series, rows...), nil
+}
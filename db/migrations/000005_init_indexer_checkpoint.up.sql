@@ -0,0 +1,11 @@
+-- Table: public.Indexer_Checkpoint
+-- Single row holding the last block fully indexed by the plugin.
+
+CREATE TABLE "Indexer_Checkpoint"
+(
+    id smallint NOT NULL DEFAULT 1,
+    block_number numeric(78,0) NOT NULL,
+    block_hash character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    CONSTRAINT "Indexer_Checkpoint_pkey" PRIMARY KEY (id),
+    CONSTRAINT "Indexer_Checkpoint_single_row" CHECK (id = 1)
+);
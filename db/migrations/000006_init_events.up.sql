@@ -0,0 +1,19 @@
+-- Table: public.Events
+-- Append-only log of every raw event the plugin applied, in block order.
+
+CREATE TABLE "Events"
+(
+    block_number numeric(78,0) NOT NULL,
+    block_hash character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    "timestamp" numeric(78,0) NOT NULL,
+    transaction_hash character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    transaction_index integer NOT NULL,
+    event_index integer NOT NULL,
+    from_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    event_name character varying COLLATE pg_catalog."default" NOT NULL,
+    keys jsonb NOT NULL,
+    data jsonb NOT NULL,
+    CONSTRAINT "Events_pkey" PRIMARY KEY (block_number, transaction_index, event_index)
+);
+
+CREATE INDEX "Events_from_address_idx" ON "Events" (from_address);
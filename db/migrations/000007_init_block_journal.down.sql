@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS vault_journal ON public."VaultStates";
+DROP TRIGGER IF EXISTS lp_journal ON public."Liquidity_Providers";
+DROP TRIGGER IF EXISTS or_journal ON public."Option_Rounds";
+DROP TRIGGER IF EXISTS ob_journal ON public."Option_Buyers";
+DROP TRIGGER IF EXISTS bids_journal ON public."Bids";
+DROP TRIGGER IF EXISTS ql_journal ON public."Queued_Liquidity";
+
+DROP FUNCTION IF EXISTS public.revert_block(numeric);
+DROP FUNCTION IF EXISTS public.journal_row_change();
+
+DROP TABLE IF EXISTS public."Block_Journal";
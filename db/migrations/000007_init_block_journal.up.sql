@@ -0,0 +1,123 @@
+-- Table: public.Block_Journal
+-- Before/after images of every row touched while indexing a block, used to
+-- restore the derived tables wholesale when Juno reverts that block.
+
+CREATE TABLE "Block_Journal"
+(
+    id bigserial NOT NULL,
+    block_number numeric(78,0) NOT NULL,
+    table_name character varying COLLATE pg_catalog."default" NOT NULL,
+    operation character varying(6) COLLATE pg_catalog."default" NOT NULL,
+    old_row jsonb,
+    new_row jsonb,
+    CONSTRAINT "Block_Journal_pkey" PRIMARY KEY (id)
+);
+
+CREATE INDEX "Block_Journal_block_number_idx" ON "Block_Journal" (block_number);
+
+-- FUNCTION: public.journal_row_change()
+-- Journals the change under the block set with
+-- set_config('pitchlake.block_number', ..., true); changes made outside a
+-- block transaction (reverts, manual fixes) are not journaled.
+
+CREATE FUNCTION public.journal_row_change()
+    RETURNS trigger
+    LANGUAGE 'plpgsql'
+    COST 100
+    VOLATILE NOT LEAKPROOF
+AS $BODY$
+DECLARE
+    journal_block text := current_setting('pitchlake.block_number', true);
+BEGIN
+    IF journal_block IS NULL OR journal_block = '' THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO "Block_Journal" (block_number, table_name, operation, old_row, new_row)
+    VALUES (
+        journal_block::numeric,
+        TG_TABLE_NAME,
+        TG_OP,
+        CASE WHEN TG_OP IN ('UPDATE', 'DELETE') THEN to_jsonb(OLD) END,
+        CASE WHEN TG_OP IN ('INSERT', 'UPDATE') THEN to_jsonb(NEW) END
+    );
+    RETURN NULL;
+END;
+$BODY$;
+
+-- FUNCTION: public.revert_block(numeric)
+-- Undoes the journaled changes of a block in reverse order. Every entry must
+-- match exactly one row in its post-change state, otherwise the revert aborts.
+
+CREATE FUNCTION public.revert_block(reverted_block numeric)
+    RETURNS void
+    LANGUAGE 'plpgsql'
+    COST 100
+    VOLATILE NOT LEAKPROOF
+AS $BODY$
+DECLARE
+    entry record;
+    row_columns text;
+    affected integer;
+BEGIN
+    PERFORM set_config('pitchlake.block_number', '', true);
+
+    FOR entry IN
+        SELECT * FROM "Block_Journal" WHERE block_number = reverted_block ORDER BY id DESC
+    LOOP
+        IF entry.operation = 'INSERT' THEN
+            EXECUTE format('DELETE FROM %I t WHERE to_jsonb(t.*) = $1', entry.table_name)
+                USING entry.new_row;
+        ELSIF entry.operation = 'UPDATE' THEN
+            SELECT string_agg(quote_ident(column_name), ', ')
+                INTO row_columns
+                FROM jsonb_object_keys(entry.old_row) AS column_name;
+            EXECUTE format(
+                'UPDATE %1$I t SET (%2$s) = (SELECT %2$s FROM jsonb_populate_record(NULL::%1$I, $1)) WHERE to_jsonb(t.*) = $2',
+                entry.table_name,
+                row_columns
+            ) USING entry.old_row, entry.new_row;
+        ELSE
+            EXECUTE format('INSERT INTO %1$I SELECT * FROM jsonb_populate_record(NULL::%1$I, $1)', entry.table_name)
+                USING entry.old_row;
+        END IF;
+
+        GET DIAGNOSTICS affected = ROW_COUNT;
+        IF affected <> 1 THEN
+            RAISE EXCEPTION 'journal entry % (% on %) matched % rows', entry.id, entry.operation, entry.table_name, affected;
+        END IF;
+    END LOOP;
+
+    DELETE FROM "Block_Journal" WHERE block_number = reverted_block;
+END;
+$BODY$;
+
+CREATE TRIGGER vault_journal
+AFTER INSERT OR UPDATE OR DELETE ON public."VaultStates"
+FOR EACH ROW
+EXECUTE FUNCTION public.journal_row_change();
+
+CREATE TRIGGER lp_journal
+AFTER INSERT OR UPDATE OR DELETE ON public."Liquidity_Providers"
+FOR EACH ROW
+EXECUTE FUNCTION public.journal_row_change();
+
+CREATE TRIGGER or_journal
+AFTER INSERT OR UPDATE OR DELETE ON public."Option_Rounds"
+FOR EACH ROW
+EXECUTE FUNCTION public.journal_row_change();
+
+CREATE TRIGGER ob_journal
+AFTER INSERT OR UPDATE OR DELETE ON public."Option_Buyers"
+FOR EACH ROW
+EXECUTE FUNCTION public.journal_row_change();
+
+CREATE TRIGGER bids_journal
+AFTER INSERT OR UPDATE OR DELETE ON public."Bids"
+FOR EACH ROW
+EXECUTE FUNCTION public.journal_row_change();
+
+CREATE TRIGGER ql_journal
+AFTER INSERT OR UPDATE OR DELETE ON public."Queued_Liquidity"
+FOR EACH ROW
+EXECUTE FUNCTION public.journal_row_change();
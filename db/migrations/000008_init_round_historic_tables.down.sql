@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS or_log_history ON public."Option_Rounds";
+DROP TRIGGER IF EXISTS bids_log_history ON public."Bids";
+DROP TRIGGER IF EXISTS ob_log_history ON public."Option_Buyers";
+DROP TRIGGER IF EXISTS ql_log_history ON public."Queued_Liquidity";
+
+DROP FUNCTION IF EXISTS public.log_row_history();
+
+DROP TABLE IF EXISTS public."Option_Rounds_Historic";
+DROP TABLE IF EXISTS public."Bids_Historic";
+DROP TABLE IF EXISTS public."Option_Buyers_Historic";
+DROP TABLE IF EXISTS public."Queued_Liquidity_Historic";
@@ -0,0 +1,124 @@
+-- Historic tables for Option_Rounds, Bids, Option_Buyers and Queued_Liquidity.
+-- Each row is the state of the source row at the end of block_number.
+
+CREATE TABLE "Option_Rounds_Historic"
+(
+    address character varying COLLATE pg_catalog."default" NOT NULL,
+    available_options numeric(78,0),
+    clearing_price numeric(78,0),
+    settlement_price numeric(78,0),
+    reserve_price numeric(78,0),
+    strike_price numeric(78,0),
+    sold_options numeric(78,0),
+    deployment_date numeric(78,0),
+    state character varying(10) COLLATE pg_catalog."default",
+    premiums numeric(78,0),
+    vault_address character varying(67) COLLATE pg_catalog."default",
+    round_id numeric(78,0),
+    cap_level numeric(78,0),
+    unsold_liquidity numeric(78,0),
+    starting_liquidity numeric(78,0),
+    queued_liquidity numeric(78,0),
+    remaining_liquidity numeric(78,0),
+    payout_per_option numeric(78,0),
+    start_date numeric(78,0),
+    end_date numeric(78,0),
+    settlement_date numeric(78,0),
+    block_number numeric(78,0) NOT NULL,
+    CONSTRAINT "Option_Rounds_Historic_pkey" PRIMARY KEY (address, block_number)
+);
+
+CREATE TABLE "Bids_Historic"
+(
+    buyer_address character varying(67) COLLATE pg_catalog."default",
+    round_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    bid_id character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    tree_nonce numeric(78,0),
+    amount numeric(78,0),
+    price numeric(78,0),
+    block_number numeric(78,0) NOT NULL,
+    CONSTRAINT "Bids_Historic_pkey" PRIMARY KEY (round_address, bid_id, block_number)
+);
+
+CREATE TABLE "Option_Buyers_Historic"
+(
+    address character varying COLLATE pg_catalog."default" NOT NULL,
+    round_address character varying COLLATE pg_catalog."default" NOT NULL,
+    has_minted boolean,
+    has_refunded boolean,
+    mintable_options numeric(78,0),
+    refundable_amount numeric(78,0),
+    block_number numeric(78,0) NOT NULL,
+    CONSTRAINT "Option_Buyers_Historic_pkey" PRIMARY KEY (address, round_address, block_number)
+);
+
+CREATE TABLE "Queued_Liquidity_Historic"
+(
+    address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    queued_liquidity numeric(78,0),
+    bps numeric(78,0),
+    round_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    block_number numeric(78,0) NOT NULL,
+    CONSTRAINT "Queued_Liquidity_Historic_pkey" PRIMARY KEY (address, round_address, block_number)
+);
+
+-- FUNCTION: public.log_row_history()
+-- Snapshots NEW into the historic table named by TG_ARGV[0] under the block
+-- being indexed. The remaining arguments are the key columns of the source
+-- table. Changes made outside a block transaction (reverts) are not logged.
+
+CREATE FUNCTION public.log_row_history()
+    RETURNS trigger
+    LANGUAGE 'plpgsql'
+    COST 100
+    VOLATILE NOT LEAKPROOF
+AS $BODY$
+DECLARE
+    history_block text := current_setting('pitchlake.block_number', true);
+    history_table text := TG_ARGV[0];
+    snapshot jsonb;
+    key_filter text := '';
+BEGIN
+    IF history_block IS NULL OR history_block = '' THEN
+        RETURN NEW;
+    END IF;
+
+    snapshot := to_jsonb(NEW) || jsonb_build_object('block_number', history_block::numeric);
+    FOR i IN 1 .. TG_NARGS - 1 LOOP
+        key_filter := key_filter || format('%1$I = ($1->>%1$L) AND ', TG_ARGV[i]);
+    END LOOP;
+
+    EXECUTE format(
+        'DELETE FROM %I WHERE %s block_number = ($1->>''block_number'')::numeric',
+        history_table,
+        key_filter
+    ) USING snapshot;
+    EXECUTE format('INSERT INTO %1$I SELECT * FROM jsonb_populate_record(NULL::%1$I, $1)', history_table)
+        USING snapshot;
+    RETURN NEW;
+END;
+$BODY$;
+
+CREATE TRIGGER or_log_history
+AFTER INSERT OR UPDATE
+ON public."Option_Rounds"
+FOR EACH ROW
+EXECUTE FUNCTION public.log_row_history('Option_Rounds_Historic', 'address');
+
+CREATE TRIGGER bids_log_history
+AFTER INSERT OR UPDATE
+ON public."Bids"
+FOR EACH ROW
+EXECUTE FUNCTION public.log_row_history('Bids_Historic', 'round_address', 'bid_id');
+
+CREATE TRIGGER ob_log_history
+AFTER INSERT OR UPDATE
+ON public."Option_Buyers"
+FOR EACH ROW
+EXECUTE FUNCTION public.log_row_history('Option_Buyers_Historic', 'address', 'round_address');
+
+CREATE TRIGGER ql_log_history
+AFTER INSERT OR UPDATE
+ON public."Queued_Liquidity"
+FOR EACH ROW
+EXECUTE FUNCTION public.log_row_history('Queued_Liquidity_Historic', 'address', 'round_address');
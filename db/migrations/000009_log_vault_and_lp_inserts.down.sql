@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS "Vault_Historic_block_number_idx";
+DROP INDEX IF EXISTS "Liquidity_Providers_Historic_block_number_idx";
+
+DROP TRIGGER IF EXISTS lp_log_update ON public."Liquidity_Providers";
+CREATE TRIGGER lp_log_update
+AFTER UPDATE
+ON public."Liquidity_Providers"
+FOR EACH ROW
+EXECUTE FUNCTION public.log_lp_update();
+
+DROP TRIGGER IF EXISTS vault_log_update ON public."VaultStates";
+CREATE TRIGGER vault_log_update
+AFTER UPDATE
+ON public."VaultStates"
+FOR EACH ROW
+EXECUTE FUNCTION public.log_vault_update();
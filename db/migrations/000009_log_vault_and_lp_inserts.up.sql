@@ -0,0 +1,19 @@
+-- Snapshot vaults and LPs when they are created too, so their state is known
+-- from the block they first appear in rather than from their first update.
+
+DROP TRIGGER IF EXISTS lp_log_update ON public."Liquidity_Providers";
+CREATE TRIGGER lp_log_update
+AFTER INSERT OR UPDATE
+ON public."Liquidity_Providers"
+FOR EACH ROW
+EXECUTE FUNCTION public.log_lp_update();
+
+DROP TRIGGER IF EXISTS vault_log_update ON public."VaultStates";
+CREATE TRIGGER vault_log_update
+AFTER INSERT OR UPDATE
+ON public."VaultStates"
+FOR EACH ROW
+EXECUTE FUNCTION public.log_vault_update();
+
+CREATE INDEX "Vault_Historic_block_number_idx" ON "Vault_Historic" (address, block_number DESC);
+CREATE INDEX "Liquidity_Providers_Historic_block_number_idx" ON "Liquidity_Providers_Historic" (vault_address, address, block_number DESC);
@@ -0,0 +1 @@
+ALTER TABLE "VaultStates" DROP COLUMN IF EXISTS class_hash;
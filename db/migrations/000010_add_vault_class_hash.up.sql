@@ -0,0 +1,4 @@
+-- Vault class hash, used to pick the event layout of the vault's contract
+-- version. Vaults indexed before this column existed keep NULL and are
+-- decoded with the default version.
+ALTER TABLE "VaultStates" ADD COLUMN class_hash character varying(67) COLLATE pg_catalog."default";
@@ -0,0 +1,24 @@
+-- Table: public.Quarantined_Events
+-- Dead-letter log of events the plugin could not decode or apply. Rows are
+-- written in the block's transaction and dropped again when the block is
+-- reverted.
+
+CREATE TABLE "Quarantined_Events"
+(
+    id bigserial NOT NULL,
+    block_number numeric(78,0) NOT NULL,
+    block_hash character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    "timestamp" numeric(78,0) NOT NULL,
+    transaction_hash character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    transaction_index integer NOT NULL,
+    event_index integer NOT NULL,
+    from_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    event_name character varying COLLATE pg_catalog."default" NOT NULL,
+    member character varying COLLATE pg_catalog."default" NOT NULL,
+    keys jsonb NOT NULL,
+    data jsonb NOT NULL,
+    error text COLLATE pg_catalog."default" NOT NULL,
+    CONSTRAINT "Quarantined_Events_pkey" PRIMARY KEY (id)
+);
+
+CREATE INDEX "Quarantined_Events_block_number_idx" ON "Quarantined_Events" (block_number);
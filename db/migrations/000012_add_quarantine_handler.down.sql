@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "Quarantined_Events_handler_idx";
+
+ALTER TABLE "Quarantined_Events"
+    DROP COLUMN IF EXISTS handler,
+    DROP COLUMN IF EXISTS retries;
@@ -0,0 +1,8 @@
+-- handler names the indexer function that rejected the event
+-- (processUDC, processVaultEvent or processRoundEvent); retries counts the
+-- failed runs of `pitchlakectl retry-quarantined`.
+ALTER TABLE "Quarantined_Events"
+    ADD COLUMN handler character varying COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+    ADD COLUMN retries integer NOT NULL DEFAULT 0;
+
+CREATE INDEX "Quarantined_Events_handler_idx" ON "Quarantined_Events" (handler);
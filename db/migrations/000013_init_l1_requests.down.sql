@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS l1_requests_journal ON public."L1_Requests";
+
+DROP TABLE IF EXISTS "L1_Requests";
@@ -0,0 +1,24 @@
+-- Table: public.L1_Requests
+-- Fossil requests fulfilled for a vault, one per round. The pricing data is
+-- the one delivered for round_id; fulfilled_block and fulfilled_at record
+-- when the vault received it.
+
+CREATE TABLE "L1_Requests"
+(
+    vault_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    round_id numeric(78,0) NOT NULL,
+    request_id character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    caller character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    fossil_client_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    strike_price numeric(78,0) NOT NULL,
+    cap_level numeric(78,0) NOT NULL,
+    reserve_price numeric(78,0) NOT NULL,
+    fulfilled_block numeric(78,0) NOT NULL,
+    fulfilled_at numeric(78,0) NOT NULL,
+    CONSTRAINT "L1_Requests_pkey" PRIMARY KEY (vault_address, round_id)
+);
+
+CREATE TRIGGER l1_requests_journal
+AFTER INSERT OR UPDATE OR DELETE ON public."L1_Requests"
+FOR EACH ROW
+EXECUTE FUNCTION public.journal_row_change();
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS oh_log_history ON public."Option_Holders";
+DROP TRIGGER IF EXISTS oh_journal ON public."Option_Holders";
+
+DROP TABLE IF EXISTS public."Option_Holders_Historic";
+DROP TABLE IF EXISTS public."Option_Holders";
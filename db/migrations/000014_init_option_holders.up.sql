@@ -0,0 +1,40 @@
+-- Table: public.Option_Holders
+-- Option token balances per round, from the round's ERC20 Transfer events.
+-- settled_balance and payout are fixed when the round settles;
+-- exercised_options counts the tokens burnt after settlement.
+
+CREATE TABLE "Option_Holders"
+(
+    round_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    balance numeric(78,0) NOT NULL DEFAULT 0,
+    settled_balance numeric(78,0) NOT NULL DEFAULT 0,
+    payout numeric(78,0) NOT NULL DEFAULT 0,
+    exercised_options numeric(78,0) NOT NULL DEFAULT 0,
+    latest_block numeric(78,0) NOT NULL,
+    CONSTRAINT "Option_Holders_pkey" PRIMARY KEY (round_address, address)
+);
+
+CREATE TABLE "Option_Holders_Historic"
+(
+    round_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    balance numeric(78,0),
+    settled_balance numeric(78,0),
+    payout numeric(78,0),
+    exercised_options numeric(78,0),
+    latest_block numeric(78,0),
+    block_number numeric(78,0) NOT NULL,
+    CONSTRAINT "Option_Holders_Historic_pkey" PRIMARY KEY (round_address, address, block_number)
+);
+
+CREATE TRIGGER oh_journal
+AFTER INSERT OR UPDATE OR DELETE ON public."Option_Holders"
+FOR EACH ROW
+EXECUTE FUNCTION public.journal_row_change();
+
+CREATE TRIGGER oh_log_history
+AFTER INSERT OR UPDATE
+ON public."Option_Holders"
+FOR EACH ROW
+EXECUTE FUNCTION public.log_row_history('Option_Holders_Historic', 'round_address', 'address');
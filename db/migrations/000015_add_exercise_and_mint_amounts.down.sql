@@ -0,0 +1,19 @@
+ALTER TABLE "Option_Rounds_Historic"
+    DROP COLUMN IF EXISTS exercised_options,
+    DROP COLUMN IF EXISTS exercised_amount,
+    DROP COLUMN IF EXISTS minted_options;
+
+ALTER TABLE "Option_Rounds"
+    DROP COLUMN IF EXISTS exercised_options,
+    DROP COLUMN IF EXISTS exercised_amount,
+    DROP COLUMN IF EXISTS minted_options;
+
+ALTER TABLE "Option_Buyers_Historic"
+    DROP COLUMN IF EXISTS exercised_options,
+    DROP COLUMN IF EXISTS exercised_amount,
+    DROP COLUMN IF EXISTS minted_options;
+
+ALTER TABLE "Option_Buyers"
+    DROP COLUMN IF EXISTS exercised_options,
+    DROP COLUMN IF EXISTS exercised_amount,
+    DROP COLUMN IF EXISTS minted_options;
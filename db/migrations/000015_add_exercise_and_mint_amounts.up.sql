@@ -0,0 +1,23 @@
+-- Amounts carried by OptionsExercised and OptionsMinted, per buyer and in
+-- total per round. exercised_amount is the payout of the exercised options.
+-- Snapshots taken before these columns existed read as 0.
+
+ALTER TABLE "Option_Buyers"
+    ADD COLUMN exercised_options numeric(78,0) NOT NULL DEFAULT 0,
+    ADD COLUMN exercised_amount numeric(78,0) NOT NULL DEFAULT 0,
+    ADD COLUMN minted_options numeric(78,0) NOT NULL DEFAULT 0;
+
+ALTER TABLE "Option_Buyers_Historic"
+    ADD COLUMN exercised_options numeric(78,0) DEFAULT 0,
+    ADD COLUMN exercised_amount numeric(78,0) DEFAULT 0,
+    ADD COLUMN minted_options numeric(78,0) DEFAULT 0;
+
+ALTER TABLE "Option_Rounds"
+    ADD COLUMN exercised_options numeric(78,0) NOT NULL DEFAULT 0,
+    ADD COLUMN exercised_amount numeric(78,0) NOT NULL DEFAULT 0,
+    ADD COLUMN minted_options numeric(78,0) NOT NULL DEFAULT 0;
+
+ALTER TABLE "Option_Rounds_Historic"
+    ADD COLUMN exercised_options numeric(78,0) DEFAULT 0,
+    ADD COLUMN exercised_amount numeric(78,0) DEFAULT 0,
+    ADD COLUMN minted_options numeric(78,0) DEFAULT 0;
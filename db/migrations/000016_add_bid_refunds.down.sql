@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS "Option_Buyers_refund_mismatch_idx";
+
+ALTER TABLE "Option_Buyers_Historic"
+    DROP COLUMN IF EXISTS refunded_amount,
+    DROP COLUMN IF EXISTS refunded_block,
+    DROP COLUMN IF EXISTS refund_mismatch;
+
+ALTER TABLE "Option_Buyers"
+    DROP COLUMN IF EXISTS refunded_amount,
+    DROP COLUMN IF EXISTS refunded_block,
+    DROP COLUMN IF EXISTS refund_mismatch;
@@ -0,0 +1,15 @@
+-- Refund paid by UnusedBidsRefunded and the block it was paid in.
+-- refund_mismatch flags buyers whose refund differs from the
+-- refundable_amount computed when the auction ended.
+
+ALTER TABLE "Option_Buyers"
+    ADD COLUMN refunded_amount numeric(78,0) NOT NULL DEFAULT 0,
+    ADD COLUMN refunded_block numeric(78,0) NOT NULL DEFAULT 0,
+    ADD COLUMN refund_mismatch boolean NOT NULL DEFAULT false;
+
+ALTER TABLE "Option_Buyers_Historic"
+    ADD COLUMN refunded_amount numeric(78,0) DEFAULT 0,
+    ADD COLUMN refunded_block numeric(78,0) DEFAULT 0,
+    ADD COLUMN refund_mismatch boolean DEFAULT false;
+
+CREATE INDEX "Option_Buyers_refund_mismatch_idx" ON "Option_Buyers" (round_address) WHERE refund_mismatch;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS lp_actions_journal ON public."LP_Actions";
+
+DROP TABLE IF EXISTS "LP_Actions";
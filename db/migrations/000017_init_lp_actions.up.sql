@@ -0,0 +1,25 @@
+-- Table: public.LP_Actions
+-- One row per deposit, withdrawal, stash withdrawal and queued withdrawal,
+-- the activity feed of liquidity providers. For queued withdrawals amount is
+-- the liquidity the LP has queued in the round after the action.
+
+CREATE TABLE "LP_Actions"
+(
+    id bigserial NOT NULL,
+    type character varying(10) COLLATE pg_catalog."default" NOT NULL,
+    amount numeric(78,0) NOT NULL,
+    vault_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    lp_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    block_number numeric(78,0) NOT NULL,
+    transaction_hash character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    "timestamp" numeric(78,0) NOT NULL,
+    CONSTRAINT "LP_Actions_pkey" PRIMARY KEY (id)
+);
+
+CREATE INDEX "LP_Actions_vault_lp_idx" ON "LP_Actions" (vault_address, lp_address, block_number);
+CREATE INDEX "LP_Actions_lp_idx" ON "LP_Actions" (lp_address, block_number);
+
+CREATE TRIGGER lp_actions_journal
+AFTER INSERT OR UPDATE OR DELETE ON public."LP_Actions"
+FOR EACH ROW
+EXECUTE FUNCTION public.journal_row_change();
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS round_metrics_journal ON public."Round_Metrics";
+
+DROP TABLE IF EXISTS "Round_Metrics";
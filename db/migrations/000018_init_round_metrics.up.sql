@@ -0,0 +1,32 @@
+-- Table: public.Round_Metrics
+-- Performance of a round for its LPs, computed when it settles:
+--   total_payout     = sold options * payout per option
+--   round_return     = (premiums - total_payout) / starting_liquidity
+--   annualized_yield = round_return compounded over a year of rounds of
+--                      round_duration seconds
+--   premium_ratio    = premiums / starting_liquidity
+--   loss_ratio       = total_payout / premiums
+
+CREATE TABLE "Round_Metrics"
+(
+    round_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    vault_address character varying(67) COLLATE pg_catalog."default" NOT NULL,
+    round_id numeric(78,0) NOT NULL,
+    starting_liquidity numeric(78,0) NOT NULL,
+    premiums numeric(78,0) NOT NULL,
+    total_payout numeric(78,0) NOT NULL,
+    round_return double precision NOT NULL,
+    annualized_yield double precision NOT NULL,
+    premium_ratio double precision NOT NULL,
+    loss_ratio double precision NOT NULL,
+    round_duration numeric(78,0) NOT NULL,
+    settled_block numeric(78,0) NOT NULL,
+    CONSTRAINT "Round_Metrics_pkey" PRIMARY KEY (round_address)
+);
+
+CREATE INDEX "Round_Metrics_vault_round_idx" ON "Round_Metrics" (vault_address, round_id);
+
+CREATE TRIGGER round_metrics_journal
+AFTER INSERT OR UPDATE OR DELETE ON public."Round_Metrics"
+FOR EACH ROW
+EXECUTE FUNCTION public.journal_row_change();
@@ -0,0 +1,22 @@
+-- Snapshot VaultStates and Liquidity_Providers with log_row_history, under
+-- the block being indexed, like the round tables. The old triggers keyed the
+-- snapshot on NEW.latest_block and also fired for the rows revert_block
+-- restores, overwriting the snapshot of an older block. Run
+-- `pitchlakectl rebuild` to regenerate snapshots written before this.
+
+DROP TRIGGER IF EXISTS lp_log_update ON public."Liquidity_Providers";
+DROP TRIGGER IF EXISTS vault_log_update ON public."VaultStates";
+DROP FUNCTION IF EXISTS public.log_lp_update;
+DROP FUNCTION IF EXISTS public.log_vault_update;
+
+CREATE TRIGGER lp_log_history
+AFTER INSERT OR UPDATE
+ON public."Liquidity_Providers"
+FOR EACH ROW
+EXECUTE FUNCTION public.log_row_history('Liquidity_Providers_Historic', 'address', 'vault_address');
+
+CREATE TRIGGER vault_log_history
+AFTER INSERT OR UPDATE
+ON public."VaultStates"
+FOR EACH ROW
+EXECUTE FUNCTION public.log_row_history('Vault_Historic', 'address');
@@ -0,0 +1,2 @@
+-- The dropped dead letters cannot be restored.
+SELECT 1;
@@ -0,0 +1,6 @@
+-- Events of tracked contracts that are not Pitchlake events (upgrades,
+-- ownership transfers, ...) are now skipped instead of quarantined. Drop the
+-- rows quarantined for that reason, no retry could ever apply them.
+
+DELETE FROM "Quarantined_Events"
+WHERE error LIKE '%: unknown vault event%' OR error LIKE '%: unknown round event%';
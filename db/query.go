@@ -0,0 +1,198 @@
+package db
+
+import (
+	"errors"
+	"junoplugin/models"
+
+	"gorm.io/gorm"
+)
+
+// Read-only queries for the API. They run on the connection rather than the
+// block transaction and return nil, nil when a single record is not found.
+
+// Page selects a window of a list query
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+func (p Page) apply(query *gorm.DB) *gorm.DB {
+	return query.Limit(p.Limit).Offset(p.Offset)
+}
+
+func first[T any](query *gorm.DB) (*T, error) {
+	var record T
+	if err := query.First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (db *DB) GetVault(address string) (*models.VaultState, error) {
+	return first[models.VaultState](db.Conn.Where("address = ?", address))
+}
+
+func (db *DB) ListVaults(page Page) ([]models.VaultState, error) {
+	var vaults []models.VaultState
+	if err := page.apply(db.Conn.Order("deployment_date ASC, address ASC")).Find(&vaults).Error; err != nil {
+		return nil, err
+	}
+	return vaults, nil
+}
+
+func (db *DB) GetOptionRound(address string) (*models.OptionRound, error) {
+	return first[models.OptionRound](db.Conn.Where("address = ?", address))
+}
+
+func (db *DB) ListOptionRoundsForVault(vaultAddress string, page Page) ([]models.OptionRound, error) {
+	var rounds []models.OptionRound
+	if err := page.apply(db.Conn.Where("vault_address = ?", vaultAddress).Order("round_id ASC")).Find(&rounds).Error; err != nil {
+		return nil, err
+	}
+	return rounds, nil
+}
+
+// GetL1Request returns the Fossil request that delivered the pricing data of
+// the vault's round roundID
+func (db *DB) GetL1Request(vaultAddress string, roundID models.BigInt) (*models.L1Request, error) {
+	return first[models.L1Request](db.Conn.Where("vault_address = ? AND round_id = ?", vaultAddress, roundID))
+}
+
+func (db *DB) GetLPPosition(vaultAddress, lpAddress string) (*models.LiquidityProviderState, error) {
+	return first[models.LiquidityProviderState](db.Conn.Where("vault_address = ? AND address = ?", vaultAddress, lpAddress))
+}
+
+func (db *DB) ListLPPositionsForVault(vaultAddress string, page Page) ([]models.LiquidityProviderState, error) {
+	var lps []models.LiquidityProviderState
+	if err := page.apply(db.Conn.Where("vault_address = ?", vaultAddress).Order("address ASC")).Find(&lps).Error; err != nil {
+		return nil, err
+	}
+	return lps, nil
+}
+
+// ListLPPositions returns the LP's position in every vault it deposited into
+func (db *DB) ListLPPositions(lpAddress string, page Page) ([]models.LiquidityProviderState, error) {
+	var lps []models.LiquidityProviderState
+	if err := page.apply(db.Conn.Where("address = ?", lpAddress).Order("vault_address ASC")).Find(&lps).Error; err != nil {
+		return nil, err
+	}
+	return lps, nil
+}
+
+func (db *DB) ListQueuedLiquidityForLP(lpAddress string, page Page) ([]models.QueuedLiquidity, error) {
+	var queued []models.QueuedLiquidity
+	if err := page.apply(db.Conn.Where("address = ?", lpAddress).Order("round_address ASC")).Find(&queued).Error; err != nil {
+		return nil, err
+	}
+	return queued, nil
+}
+
+func (db *DB) ListQueuedLiquidityForRound(roundAddress string, page Page) ([]models.QueuedLiquidity, error) {
+	var queued []models.QueuedLiquidity
+	if err := page.apply(db.Conn.Where("round_address = ?", roundAddress).Order("address ASC")).Find(&queued).Error; err != nil {
+		return nil, err
+	}
+	return queued, nil
+}
+
+// ListQueuedLiquidityForLPInVault returns the LP's queued withdrawals across
+// the vault's rounds
+func (db *DB) ListQueuedLiquidityForLPInVault(vaultAddress, lpAddress string, page Page) ([]models.QueuedLiquidity, error) {
+	var queued []models.QueuedLiquidity
+	rounds := db.Conn.Model(&models.OptionRound{}).Select("address").Where("vault_address = ?", vaultAddress)
+	if err := page.apply(db.Conn.Where("address = ? AND round_address IN (?)", lpAddress, rounds).Order("round_address ASC")).Find(&queued).Error; err != nil {
+		return nil, err
+	}
+	return queued, nil
+}
+
+// ListBidsForRound returns the round's bids in clearing order
+func (db *DB) ListBidsForRound(roundAddress string, page Page) ([]models.Bid, error) {
+	var bids []models.Bid
+	if err := page.apply(db.Conn.Where("round_address = ?", roundAddress).Order("price DESC, tree_nonce ASC")).Find(&bids).Error; err != nil {
+		return nil, err
+	}
+	return bids, nil
+}
+
+func (db *DB) ListBidsForBuyer(roundAddress, buyerAddress string, page Page) ([]models.Bid, error) {
+	var bids []models.Bid
+	if err := page.apply(db.Conn.Where("round_address = ? AND buyer_address = ?", roundAddress, buyerAddress).Order("price DESC, tree_nonce ASC")).Find(&bids).Error; err != nil {
+		return nil, err
+	}
+	return bids, nil
+}
+
+func (db *DB) GetOptionBuyer(roundAddress, buyerAddress string) (*models.OptionBuyer, error) {
+	return first[models.OptionBuyer](db.Conn.Where("round_address = ? AND address = ?", roundAddress, buyerAddress))
+}
+
+func (db *DB) ListOptionBuyersForRound(roundAddress string, page Page) ([]models.OptionBuyer, error) {
+	var buyers []models.OptionBuyer
+	if err := page.apply(db.Conn.Where("round_address = ?", roundAddress).Order("address ASC")).Find(&buyers).Error; err != nil {
+		return nil, err
+	}
+	return buyers, nil
+}
+
+// ListRefundMismatches returns the round's buyers whose on-chain refund
+// differs from their indexed refundable amount
+func (db *DB) ListRefundMismatches(roundAddress string, page Page) ([]models.OptionBuyer, error) {
+	var buyers []models.OptionBuyer
+	if err := page.apply(db.Conn.Where("round_address = ? AND refund_mismatch", roundAddress).Order("address ASC")).Find(&buyers).Error; err != nil {
+		return nil, err
+	}
+	return buyers, nil
+}
+
+func (db *DB) ListOptionHoldersForRound(roundAddress string, page Page) ([]models.OptionHolder, error) {
+	var holders []models.OptionHolder
+	if err := page.apply(db.Conn.Where("round_address = ?", roundAddress).Order("address ASC")).Find(&holders).Error; err != nil {
+		return nil, err
+	}
+	return holders, nil
+}
+
+func (db *DB) GetOptionHolder(roundAddress, address string) (*models.OptionHolder, error) {
+	return first[models.OptionHolder](db.Conn.Where("round_address = ? AND address = ?", roundAddress, address))
+}
+
+// ListLPActions returns the LP's actions, newest first, in one vault or in
+// every vault when vaultAddress is empty
+func (db *DB) ListLPActions(vaultAddress, lpAddress string, page Page) ([]models.LPAction, error) {
+	query := db.Conn.Where("lp_address = ?", lpAddress)
+	if vaultAddress != "" {
+		query = query.Where("vault_address = ?", vaultAddress)
+	}
+	var actions []models.LPAction
+	if err := page.apply(query.Order("block_number DESC, id DESC")).Find(&actions).Error; err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// ListVaultLPActions returns the actions of every LP of the vault, newest first
+func (db *DB) ListVaultLPActions(vaultAddress string, page Page) ([]models.LPAction, error) {
+	var actions []models.LPAction
+	if err := page.apply(db.Conn.Where("vault_address = ?", vaultAddress).Order("block_number DESC, id DESC")).Find(&actions).Error; err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+func (db *DB) GetRoundMetrics(roundAddress string) (*models.RoundMetrics, error) {
+	return first[models.RoundMetrics](db.Conn.Where("round_address = ?", roundAddress))
+}
+
+// ListRoundMetricsForVault returns the metrics of the vault's settled
+// rounds, latest round first
+func (db *DB) ListRoundMetricsForVault(vaultAddress string, page Page) ([]models.RoundMetrics, error) {
+	var roundMetrics []models.RoundMetrics
+	if err := page.apply(db.Conn.Where("vault_address = ?", vaultAddress).Order("round_id DESC")).Find(&roundMetrics).Error; err != nil {
+		return nil, err
+	}
+	return roundMetrics, nil
+}
@@ -1,133 +1,47 @@
 package db
 
 import (
-	"junoplugin/models"
-
-	"gorm.io/gorm"
+	"fmt"
 )
 
-func (db *DB) DepositOrWithdrawRevert(vaultAddress, lpAddress string, blockNumber uint64) error {
-	//Map the other parameters as well
-
-	if err := db.RevertVaultState(vaultAddress, blockNumber); err != nil {
-		return err
-	}
-
-	if err := db.RevertLPState(vaultAddress, lpAddress, blockNumber); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (db *DB) WithdrawalQueuedRevertIndex(
-	lpAddress, vaultAddress string,
-	roundId uint64,
-	bps, accountQueuedBefore, accountQueuedNow, vaultQueuedNow models.BigInt,
-	blockNumber uint64,
-) error {
-
-	db.RevertVaultState(vaultAddress, blockNumber)
-	db.RevertLPState(vaultAddress, lpAddress, blockNumber)
-
-	vault, err := db.GetVaultByAddress(vaultAddress)
-	if err != nil {
-		return err
-	}
-	queuedLiquidity := models.QueuedLiquidity{
-		Address:      lpAddress,
-		RoundAddress: vault.CurrentRoundAddress,
-		Bps:          bps,
-		QueuedAmount: accountQueuedBefore,
-	}
-	if err := db.UpsertQueuedLiquidity(&queuedLiquidity); err != nil {
-		return err
-	}
-
-	var vaultQueued, diff models.BigInt
-	diff.Sub(accountQueuedBefore.Int, accountQueuedNow.Int)
-	diff.Abs(diff.Int)
-	if accountQueuedBefore.Cmp(accountQueuedNow.Int) < 0 {
-		vaultQueued.Sub(vaultQueuedNow.Int, diff.Int)
-	} else {
-		vaultQueued.Add(vaultQueuedNow.Int, diff.Int)
-	}
-	if err := db.UpdateOptionRoundFields(vault.CurrentRoundAddress, map[string]interface{}{
-		"queued_liquidity": vaultQueued,
-	}); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (db *DB) RoundDeployedRevert(roundAddress string) {
-
-	db.DeleteOptionRound(roundAddress)
+// historicTables hold per-block snapshots written by the logging triggers.
+// They are not journaled; a revert drops every snapshot taken at or after the
+// reverted block instead.
+var historicTables = []string{
+	"Vault_Historic",
+	"Liquidity_Providers_Historic",
+	"Option_Rounds_Historic",
+	"Bids_Historic",
+	"Option_Buyers_Historic",
+	"Queued_Liquidity_Historic",
+	"Option_Holders_Historic",
 }
 
-func (db *DB) AuctionStartedRevert(vaultAddress, roundAddress string, blockNumber uint64) error {
-	if err := db.RevertVaultState(vaultAddress, blockNumber); err != nil {
-		return err
-	}
-	if err := db.RevertAllLPState(vaultAddress, blockNumber); err != nil {
-		return err
-	}
-	if err := db.UpdateOptionRoundFields(roundAddress, map[string]interface{}{
-		"available_options":  0,
-		"starting_liquidity": 0,
-		"state":              "Open",
-	}); err != nil {
-		return err
-	}
-	return nil
+// SetJournalBlock makes the journal triggers record every change made by the
+// rest of the transaction under blockNumber
+func (db *DB) SetJournalBlock(blockNumber uint64) error {
+	return db.tx.Exec("SELECT set_config('pitchlake.block_number', ?, true)", fmt.Sprint(blockNumber)).Error
 }
 
-func (db *DB) AuctionEndedRevert(vaultAddress, roundAddress string, blockNumber uint64) error {
-	if err := db.RevertVaultState(vaultAddress, blockNumber); err != nil {
+// RevertBlock restores every journaled table to its state before blockNumber
+// was indexed and drops the historic snapshots taken from that block on
+func (db *DB) RevertBlock(blockNumber uint64) error {
+	if err := db.tx.Exec("SELECT revert_block(?)", blockNumber).Error; err != nil {
 		return err
 	}
-	if err := db.RevertAllLPState(vaultAddress, blockNumber); err != nil {
-		return err
-	}
-	if err := db.UpdateOptionRoundFields(roundAddress, map[string]interface{}{
-		"clearing_price": nil,
-		"options_sold":   nil,
-		"state":          "Auctioning",
-	}); err != nil {
-		return err
-	}
-	if err := db.UpdateAllOptionBuyerFields(roundAddress, map[string]interface{}{
-		"tokenizable_options": 0,
-		"refundable_amount":   0,
-	}); err != nil {
-		return err
+	for _, table := range historicTables {
+		if err := db.tx.Exec(
+			fmt.Sprintf(`DELETE FROM %q WHERE block_number >= ?`, table),
+			blockNumber,
+		).Error; err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (db *DB) RoundSettledRevert(vaultAddress, roundAddress string, blockNumber uint64) error {
-	if err := db.RevertVaultState(vaultAddress, blockNumber); err != nil {
-		return err
-	}
-	if err := db.RevertAllLPState(vaultAddress, blockNumber); err != nil {
-		return err
-	}
-	err := db.UpdateOptionRoundFields(roundAddress, map[string]interface{}{
-		"settlement_price": 0,
-		"total_payout":     0,
-		"state":            "Running",
-	})
-	return err
-}
-
-func (db *DB) BidAcceptedRevert(bidId, roundAddress string) error {
-	err := db.DeleteBid(bidId, roundAddress)
-	return err
-}
-
-func (db *DB) BidUpdatedRevert(bidId string, amount models.BigInt, treeNonce uint64) {
-	db.tx.Model(models.Bid{}).Where("bid_id = ?", bidId).Updates(map[string]interface{}{
-		"amount":     gorm.Expr("amount - ?", amount),
-		"tree_nonce": treeNonce,
-	})
-
+// PruneJournal drops journal entries for blocks older than the given block,
+// which can no longer be reverted
+func (db *DB) PruneJournal(beforeBlock uint64) error {
+	return db.tx.Exec(`DELETE FROM "Block_Journal" WHERE block_number < ?`, beforeBlock).Error
 }
@@ -0,0 +1,218 @@
+// Package gql serves a GraphQL schema over the indexed Pitchlake models, with
+// subscriptions fed by the notify listener.
+package gql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"junoplugin/db"
+	"junoplugin/notify"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+const (
+	maxDepth     = 10
+	writeTimeout = 10 * time.Second
+	// subprotocol is the graphql-ws library's protocol, spoken by Apollo and urql
+	subprotocol = "graphql-transport-ws"
+)
+
+type Resolver struct {
+	db       *db.DB
+	listener *notify.Listener
+}
+
+// Handler answers queries POSTed as JSON and runs queries and subscriptions
+// over WebSocket connections using the graphql-transport-ws protocol
+type Handler struct {
+	schema         *graphql.Schema
+	http           *relay.Handler
+	allowedOrigins []string
+	log            *log.Logger
+}
+
+func NewHandler(database *db.DB, listener *notify.Listener, allowedOrigins []string, logger *log.Logger) (*Handler, error) {
+	schema, err := graphql.ParseSchema(
+		schemaSource,
+		&Resolver{db: database, listener: listener},
+		graphql.MaxDepth(maxDepth),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{
+		schema:         schema,
+		http:           &relay.Handler{Schema: schema},
+		allowedOrigins: allowedOrigins,
+		log:            logger,
+	}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		h.serveWebSocket(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "GraphQL queries must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.http.ServeHTTP(w, r)
+}
+
+type wsMessage struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type wsRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type wsConn struct {
+	conn       *websocket.Conn
+	mu         sync.Mutex
+	operations map[string]context.CancelFunc
+}
+
+func (h *Handler) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		Subprotocols:   []string{subprotocol},
+		OriginPatterns: h.allowedOrigins,
+	})
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+	if conn.Subprotocol() != subprotocol {
+		conn.Close(websocket.StatusPolicyViolation, "unsupported subprotocol, use "+subprotocol)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	c := &wsConn{conn: conn, operations: make(map[string]context.CancelFunc)}
+	err = h.readLoop(ctx, c)
+	if status := websocket.CloseStatus(err); status != websocket.StatusNormalClosure && status != websocket.StatusGoingAway {
+		h.log.Printf("GraphQL WebSocket client %s: %v", r.RemoteAddr, err)
+	}
+}
+
+func (h *Handler) readLoop(ctx context.Context, c *wsConn) error {
+	acknowledged := false
+	for {
+		var msg wsMessage
+		if err := wsjson.Read(ctx, c.conn, &msg); err != nil {
+			return err
+		}
+		switch msg.Type {
+		case "connection_init":
+			if acknowledged {
+				return c.conn.Close(4429, "Too many initialisation requests")
+			}
+			acknowledged = true
+			if err := c.write(ctx, wsMessage{Type: "connection_ack"}); err != nil {
+				return err
+			}
+		case "ping":
+			if err := c.write(ctx, wsMessage{Type: "pong"}); err != nil {
+				return err
+			}
+		case "pong":
+		case "subscribe":
+			if !acknowledged {
+				return c.conn.Close(4401, "Unauthorized")
+			}
+			var request wsRequest
+			if err := json.Unmarshal(msg.Payload, &request); err != nil {
+				return c.conn.Close(4400, "Invalid subscribe payload")
+			}
+			if !c.start(ctx, msg.ID, request, h.schema) {
+				return c.conn.Close(4409, "Subscriber for "+msg.ID+" already exists")
+			}
+		case "complete":
+			c.stop(msg.ID)
+		default:
+			return c.conn.Close(4400, "Unknown message type "+msg.Type)
+		}
+	}
+}
+
+// start runs the operation in the background, sending each response as a
+// "next" message and a final "complete" unless the client stopped it first
+func (c *wsConn) start(ctx context.Context, id string, request wsRequest, schema *graphql.Schema) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.operations[id]; ok {
+		return false
+	}
+	opCtx, cancel := context.WithCancel(ctx)
+	c.operations[id] = cancel
+
+	responses, err := schema.Subscribe(opCtx, request.Query, request.OperationName, request.Variables)
+	if err != nil {
+		// Only returned for a schema without resolver or subscriptions
+		delete(c.operations, id)
+		cancel()
+		c.write(ctx, wsMessage{Type: "error", ID: id, Payload: errorPayload(err)})
+		return true
+	}
+	go func() {
+		defer func() {
+			c.stop(id)
+			// Let the executor observe the cancellation and close the channel
+			for range responses {
+			}
+		}()
+		for response := range responses {
+			payload, err := json.Marshal(response)
+			if err != nil {
+				return
+			}
+			if err := c.write(opCtx, wsMessage{Type: "next", ID: id, Payload: payload}); err != nil {
+				return
+			}
+		}
+		if opCtx.Err() == nil {
+			c.write(ctx, wsMessage{Type: "complete", ID: id})
+		}
+	}()
+	return true
+}
+
+func (c *wsConn) stop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cancel, ok := c.operations[id]; ok {
+		cancel()
+		delete(c.operations, id)
+	}
+}
+
+func (c *wsConn) write(ctx context.Context, msg wsMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	return wsjson.Write(ctx, c.conn, msg)
+}
+
+func errorPayload(err error) json.RawMessage {
+	payload, _ := json.Marshal([]map[string]string{{"message": err.Error()}})
+	return payload
+}
@@ -0,0 +1,540 @@
+package gql
+
+import (
+	"errors"
+	"fmt"
+	"junoplugin/db"
+	"junoplugin/hexutil"
+	"junoplugin/metrics"
+	"junoplugin/models"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+	// defaultMetricsWindow matches the REST API's default for vault metrics
+	defaultMetricsWindow = 10
+)
+
+type pageArgs struct {
+	Limit  *int32
+	Offset *int32
+}
+
+// page validates the arguments like the REST API's limit and offset
+// parameters, rejecting out of range values instead of clamping them
+func (a pageArgs) page() (db.Page, error) {
+	page := db.Page{Limit: defaultLimit}
+	if a.Limit != nil {
+		if *a.Limit < 1 || *a.Limit > maxLimit {
+			return page, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+		page.Limit = int(*a.Limit)
+	}
+	if a.Offset != nil {
+		if *a.Offset < 0 {
+			return page, errors.New("offset must be a non-negative number")
+		}
+		page.Offset = int(*a.Offset)
+	}
+	return page, nil
+}
+
+// normalize converts a client supplied address to the stored form
+func normalize(address string) (string, error) {
+	return hexutil.NormalizeHexString(address)
+}
+
+// normalizeOptional normalizes an optional filter argument, "" meaning any
+func normalizeOptional(address *string) (string, error) {
+	if address == nil {
+		return "", nil
+	}
+	return normalize(*address)
+}
+
+func (r *Resolver) Vaults(args pageArgs) ([]*vaultResolver, error) {
+	page, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	vaults, err := r.db.ListVaults(page)
+	if err != nil {
+		return nil, err
+	}
+	return wrapAll(r.db, vaults, newVaultResolver), nil
+}
+
+func (r *Resolver) Vault(args struct{ Address string }) (*vaultResolver, error) {
+	address, err := normalize(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	vault, err := r.db.GetVault(address)
+	if err != nil || vault == nil {
+		return nil, err
+	}
+	return newVaultResolver(r.db, vault), nil
+}
+
+func (r *Resolver) Round(args struct{ Address string }) (*roundResolver, error) {
+	address, err := normalize(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	return resolveRound(r.db, address)
+}
+
+func (r *Resolver) LiquidityProvider(args struct {
+	Vault   string
+	Address string
+}) (*lpResolver, error) {
+	vault, err := normalize(args.Vault)
+	if err != nil {
+		return nil, err
+	}
+	address, err := normalize(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	lp, err := r.db.GetLPPosition(vault, address)
+	if err != nil || lp == nil {
+		return nil, err
+	}
+	return newLPResolver(r.db, lp), nil
+}
+
+// wrapAll wraps every record of a list query in its resolver
+func wrapAll[M any, R any](database *db.DB, records []M, wrap func(*db.DB, *M) *R) []*R {
+	resolvers := make([]*R, len(records))
+	for i := range records {
+		resolvers[i] = wrap(database, &records[i])
+	}
+	return resolvers
+}
+
+func resolveVault(database *db.DB, address string) (*vaultResolver, error) {
+	vault, err := database.GetVault(address)
+	if err != nil || vault == nil {
+		return nil, err
+	}
+	return newVaultResolver(database, vault), nil
+}
+
+func resolveRound(database *db.DB, address string) (*roundResolver, error) {
+	if address == "" {
+		return nil, nil
+	}
+	round, err := database.GetOptionRound(address)
+	if err != nil || round == nil {
+		return nil, err
+	}
+	return newRoundResolver(database, round), nil
+}
+
+type vaultResolver struct {
+	db *db.DB
+	v  *models.VaultState
+}
+
+func newVaultResolver(database *db.DB, vault *models.VaultState) *vaultResolver {
+	return &vaultResolver{db: database, v: vault}
+}
+
+func (r *vaultResolver) Address() string              { return r.v.Address }
+func (r *vaultResolver) ClassHash() string            { return r.v.ClassHash }
+func (r *vaultResolver) CurrentRound() BigInt         { return newBigInt(r.v.CurrentRound) }
+func (r *vaultResolver) CurrentRoundAddress() string  { return r.v.CurrentRoundAddress }
+func (r *vaultResolver) UnlockedBalance() BigInt      { return newBigInt(r.v.UnlockedBalance) }
+func (r *vaultResolver) LockedBalance() BigInt        { return newBigInt(r.v.LockedBalance) }
+func (r *vaultResolver) StashedBalance() BigInt       { return newBigInt(r.v.StashedBalance) }
+func (r *vaultResolver) LatestBlock() Uint64          { return Uint64(r.v.LatestBlock) }
+func (r *vaultResolver) FossilClientAddress() string  { return r.v.FossilClientAddress }
+func (r *vaultResolver) EthAddress() string           { return r.v.EthAddress }
+func (r *vaultResolver) OptionRoundClassHash() string { return r.v.OptionRoundClassHash }
+func (r *vaultResolver) Alpha() BigInt                { return newBigInt(r.v.Alpha) }
+func (r *vaultResolver) StrikeLevel() BigInt          { return newBigInt(r.v.StrikeLevel) }
+func (r *vaultResolver) RoundTransitionPeriod() Uint64 {
+	return Uint64(r.v.RoundTransitionPeriod)
+}
+func (r *vaultResolver) AuctionDuration() Uint64 { return Uint64(r.v.AuctionDuration) }
+func (r *vaultResolver) RoundDuration() Uint64   { return Uint64(r.v.RoundDuration) }
+func (r *vaultResolver) DeploymentDate() Uint64  { return Uint64(r.v.DeploymentDate) }
+
+func (r *vaultResolver) CurrentOptionRound() (*roundResolver, error) {
+	return resolveRound(r.db, r.v.CurrentRoundAddress)
+}
+
+func (r *vaultResolver) Rounds(args pageArgs) ([]*roundResolver, error) {
+	page, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	rounds, err := r.db.ListOptionRoundsForVault(r.v.Address, page)
+	if err != nil {
+		return nil, err
+	}
+	return wrapAll(r.db, rounds, newRoundResolver), nil
+}
+
+func (r *vaultResolver) LiquidityProviders(args pageArgs) ([]*lpResolver, error) {
+	page, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	lps, err := r.db.ListLPPositionsForVault(r.v.Address, page)
+	if err != nil {
+		return nil, err
+	}
+	return wrapAll(r.db, lps, newLPResolver), nil
+}
+
+func (r *vaultResolver) Metrics(args struct{ Rounds *int32 }) (*vaultMetricsResolver, error) {
+	window := defaultMetricsWindow
+	if args.Rounds != nil {
+		if *args.Rounds < 1 || *args.Rounds > maxLimit {
+			return nil, fmt.Errorf("rounds must be between 1 and %d", maxLimit)
+		}
+		window = int(*args.Rounds)
+	}
+	roundMetrics, err := r.db.ListRoundMetricsForVault(r.v.Address, db.Page{Limit: window})
+	if err != nil {
+		return nil, err
+	}
+	aggregate := metrics.ForVault(r.v.Address, roundMetrics)
+	return &vaultMetricsResolver{m: &aggregate}, nil
+}
+
+func (r *vaultResolver) RoundMetrics(args pageArgs) ([]*roundMetricsResolver, error) {
+	page, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	roundMetrics, err := r.db.ListRoundMetricsForVault(r.v.Address, page)
+	if err != nil {
+		return nil, err
+	}
+	return wrapAll(r.db, roundMetrics, newRoundMetricsResolver), nil
+}
+
+type roundResolver struct {
+	db *db.DB
+	r  *models.OptionRound
+}
+
+func newRoundResolver(database *db.DB, round *models.OptionRound) *roundResolver {
+	return &roundResolver{db: database, r: round}
+}
+
+func (r *roundResolver) Address() string            { return r.r.Address }
+func (r *roundResolver) VaultAddress() string       { return r.r.VaultAddress }
+func (r *roundResolver) RoundId() BigInt            { return newBigInt(r.r.RoundID) }
+func (r *roundResolver) State() string              { return r.r.State }
+func (r *roundResolver) CapLevel() BigInt           { return newBigInt(r.r.CapLevel) }
+func (r *roundResolver) StrikePrice() BigInt        { return newBigInt(r.r.StrikePrice) }
+func (r *roundResolver) ReservePrice() BigInt       { return newBigInt(r.r.ReservePrice) }
+func (r *roundResolver) ClearingPrice() BigInt      { return newBigInt(r.r.ClearingPrice) }
+func (r *roundResolver) SettlementPrice() BigInt    { return newBigInt(r.r.SettlementPrice) }
+func (r *roundResolver) AvailableOptions() BigInt   { return newBigInt(r.r.AvailableOptions) }
+func (r *roundResolver) SoldOptions() BigInt        { return newBigInt(r.r.SoldOptions) }
+func (r *roundResolver) Premiums() BigInt           { return newBigInt(r.r.Premiums) }
+func (r *roundResolver) PayoutPerOption() BigInt    { return newBigInt(r.r.PayoutPerOption) }
+func (r *roundResolver) StartingLiquidity() BigInt  { return newBigInt(r.r.StartingLiquidity) }
+func (r *roundResolver) QueuedLiquidity() BigInt    { return newBigInt(r.r.QueuedLiquidity) }
+func (r *roundResolver) RemainingLiquidity() BigInt { return newBigInt(r.r.RemainingLiquidity) }
+func (r *roundResolver) UnsoldLiquidity() BigInt    { return newBigInt(r.r.UnsoldLiquidity) }
+func (r *roundResolver) ExercisedOptions() BigInt   { return newBigInt(r.r.ExercisedOptions) }
+func (r *roundResolver) ExercisedAmount() BigInt    { return newBigInt(r.r.ExercisedAmount) }
+func (r *roundResolver) MintedOptions() BigInt      { return newBigInt(r.r.MintedOptions) }
+func (r *roundResolver) DeploymentDate() Uint64     { return Uint64(r.r.DeploymentDate) }
+func (r *roundResolver) StartDate() Uint64          { return Uint64(r.r.StartDate) }
+func (r *roundResolver) EndDate() Uint64            { return Uint64(r.r.EndDate) }
+func (r *roundResolver) SettlementDate() Uint64     { return Uint64(r.r.SettlementDate) }
+
+func (r *roundResolver) L1Request() (*l1RequestResolver, error) {
+	request, err := r.db.GetL1Request(r.r.VaultAddress, r.r.RoundID)
+	if err != nil || request == nil {
+		return nil, err
+	}
+	return &l1RequestResolver{l: request}, nil
+}
+
+func (r *roundResolver) Metrics() (*roundMetricsResolver, error) {
+	roundMetrics, err := r.db.GetRoundMetrics(r.r.Address)
+	if err != nil || roundMetrics == nil {
+		return nil, err
+	}
+	return &roundMetricsResolver{m: roundMetrics}, nil
+}
+
+func (r *roundResolver) Vault() (*vaultResolver, error) {
+	return resolveVault(r.db, r.r.VaultAddress)
+}
+
+func (r *roundResolver) Bids(args pageArgs) ([]*bidResolver, error) {
+	page, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	bids, err := r.db.ListBidsForRound(r.r.Address, page)
+	if err != nil {
+		return nil, err
+	}
+	return wrapAll(r.db, bids, newBidResolver), nil
+}
+
+func (r *roundResolver) Buyers(args pageArgs) ([]*buyerResolver, error) {
+	page, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	buyers, err := r.db.ListOptionBuyersForRound(r.r.Address, page)
+	if err != nil {
+		return nil, err
+	}
+	return wrapAll(r.db, buyers, newBuyerResolver), nil
+}
+
+func (r *roundResolver) Holders(args pageArgs) ([]*holderResolver, error) {
+	page, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	holders, err := r.db.ListOptionHoldersForRound(r.r.Address, page)
+	if err != nil {
+		return nil, err
+	}
+	return wrapAll(r.db, holders, newHolderResolver), nil
+}
+
+func (r *roundResolver) QueuedWithdrawals(args pageArgs) ([]*queuedResolver, error) {
+	page, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	queued, err := r.db.ListQueuedLiquidityForRound(r.r.Address, page)
+	if err != nil {
+		return nil, err
+	}
+	return wrapAll(r.db, queued, newQueuedResolver), nil
+}
+
+type l1RequestResolver struct {
+	l *models.L1Request
+}
+
+func (r *l1RequestResolver) VaultAddress() string        { return r.l.VaultAddress }
+func (r *l1RequestResolver) RoundId() BigInt             { return newBigInt(r.l.RoundID) }
+func (r *l1RequestResolver) RequestId() string           { return r.l.RequestID }
+func (r *l1RequestResolver) Caller() string              { return r.l.Caller }
+func (r *l1RequestResolver) FossilClientAddress() string { return r.l.FossilClientAddress }
+func (r *l1RequestResolver) StrikePrice() BigInt         { return newBigInt(r.l.StrikePrice) }
+func (r *l1RequestResolver) CapLevel() BigInt            { return newBigInt(r.l.CapLevel) }
+func (r *l1RequestResolver) ReservePrice() BigInt        { return newBigInt(r.l.ReservePrice) }
+func (r *l1RequestResolver) FulfilledBlock() Uint64      { return Uint64(r.l.FulfilledBlock) }
+func (r *l1RequestResolver) FulfilledAt() Uint64         { return Uint64(r.l.FulfilledAt) }
+
+type roundMetricsResolver struct {
+	m *models.RoundMetrics
+}
+
+func newRoundMetricsResolver(_ *db.DB, roundMetrics *models.RoundMetrics) *roundMetricsResolver {
+	return &roundMetricsResolver{m: roundMetrics}
+}
+
+func (r *roundMetricsResolver) RoundAddress() string      { return r.m.RoundAddress }
+func (r *roundMetricsResolver) VaultAddress() string      { return r.m.VaultAddress }
+func (r *roundMetricsResolver) RoundId() BigInt           { return newBigInt(r.m.RoundID) }
+func (r *roundMetricsResolver) StartingLiquidity() BigInt { return newBigInt(r.m.StartingLiquidity) }
+func (r *roundMetricsResolver) Premiums() BigInt          { return newBigInt(r.m.Premiums) }
+func (r *roundMetricsResolver) TotalPayout() BigInt       { return newBigInt(r.m.TotalPayout) }
+func (r *roundMetricsResolver) RoundReturn() float64      { return r.m.RoundReturn }
+func (r *roundMetricsResolver) AnnualizedYield() float64  { return r.m.AnnualizedYield }
+func (r *roundMetricsResolver) PremiumRatio() float64     { return r.m.PremiumRatio }
+func (r *roundMetricsResolver) LossRatio() float64        { return r.m.LossRatio }
+func (r *roundMetricsResolver) RoundDuration() Uint64     { return Uint64(r.m.RoundDuration) }
+func (r *roundMetricsResolver) SettledBlock() Uint64      { return Uint64(r.m.SettledBlock) }
+
+type vaultMetricsResolver struct {
+	m *models.VaultMetrics
+}
+
+func (r *vaultMetricsResolver) VaultAddress() string      { return r.m.VaultAddress }
+func (r *vaultMetricsResolver) Rounds() int32             { return int32(r.m.Rounds) }
+func (r *vaultMetricsResolver) StartingLiquidity() BigInt { return newBigInt(r.m.StartingLiquidity) }
+func (r *vaultMetricsResolver) Premiums() BigInt          { return newBigInt(r.m.Premiums) }
+func (r *vaultMetricsResolver) TotalPayout() BigInt       { return newBigInt(r.m.TotalPayout) }
+func (r *vaultMetricsResolver) AverageRoundReturn() float64 {
+	return r.m.AverageRoundReturn
+}
+func (r *vaultMetricsResolver) AverageAnnualizedYield() float64 {
+	return r.m.AverageAnnualizedYield
+}
+func (r *vaultMetricsResolver) CumulativeReturn() float64 { return r.m.CumulativeReturn }
+func (r *vaultMetricsResolver) PremiumRatio() float64     { return r.m.PremiumRatio }
+func (r *vaultMetricsResolver) LossRatio() float64        { return r.m.LossRatio }
+
+type bidResolver struct {
+	db *db.DB
+	b  *models.Bid
+}
+
+func newBidResolver(database *db.DB, bid *models.Bid) *bidResolver {
+	return &bidResolver{db: database, b: bid}
+}
+
+func (r *bidResolver) BidId() string        { return r.b.BidID }
+func (r *bidResolver) BuyerAddress() string { return r.b.BuyerAddress }
+func (r *bidResolver) RoundAddress() string { return r.b.RoundAddress }
+func (r *bidResolver) TreeNonce() Uint64    { return Uint64(r.b.TreeNonce) }
+func (r *bidResolver) Amount() BigInt       { return newBigInt(r.b.Amount) }
+func (r *bidResolver) Price() BigInt        { return newBigInt(r.b.Price) }
+
+func (r *bidResolver) Round() (*roundResolver, error) {
+	return resolveRound(r.db, r.b.RoundAddress)
+}
+
+func (r *bidResolver) Buyer() (*buyerResolver, error) {
+	buyer, err := r.db.GetOptionBuyer(r.b.RoundAddress, r.b.BuyerAddress)
+	if err != nil || buyer == nil {
+		return nil, err
+	}
+	return newBuyerResolver(r.db, buyer), nil
+}
+
+type buyerResolver struct {
+	db *db.DB
+	b  *models.OptionBuyer
+}
+
+func newBuyerResolver(database *db.DB, buyer *models.OptionBuyer) *buyerResolver {
+	return &buyerResolver{db: database, b: buyer}
+}
+
+func (r *buyerResolver) Address() string          { return r.b.Address }
+func (r *buyerResolver) RoundAddress() string     { return r.b.RoundAddress }
+func (r *buyerResolver) MintableOptions() BigInt  { return newBigInt(r.b.MintableOptions) }
+func (r *buyerResolver) HasMinted() bool          { return r.b.HasMinted }
+func (r *buyerResolver) RefundableAmount() BigInt { return newBigInt(r.b.RefundableAmount) }
+func (r *buyerResolver) HasRefunded() bool        { return r.b.HasRefunded }
+func (r *buyerResolver) ExercisedOptions() BigInt { return newBigInt(r.b.ExercisedOptions) }
+func (r *buyerResolver) ExercisedAmount() BigInt  { return newBigInt(r.b.ExercisedAmount) }
+func (r *buyerResolver) MintedOptions() BigInt    { return newBigInt(r.b.MintedOptions) }
+func (r *buyerResolver) RefundedAmount() BigInt   { return newBigInt(r.b.RefundedAmount) }
+func (r *buyerResolver) RefundedBlock() Uint64    { return Uint64(r.b.RefundedBlock) }
+func (r *buyerResolver) RefundMismatch() bool     { return r.b.RefundMismatch }
+
+func (r *buyerResolver) Round() (*roundResolver, error) {
+	return resolveRound(r.db, r.b.RoundAddress)
+}
+
+func (r *buyerResolver) Bids(args pageArgs) ([]*bidResolver, error) {
+	page, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	bids, err := r.db.ListBidsForBuyer(r.b.RoundAddress, r.b.Address, page)
+	if err != nil {
+		return nil, err
+	}
+	return wrapAll(r.db, bids, newBidResolver), nil
+}
+
+type holderResolver struct {
+	db *db.DB
+	h  *models.OptionHolder
+}
+
+func newHolderResolver(database *db.DB, holder *models.OptionHolder) *holderResolver {
+	return &holderResolver{db: database, h: holder}
+}
+
+func (r *holderResolver) Address() string          { return r.h.Address }
+func (r *holderResolver) RoundAddress() string     { return r.h.RoundAddress }
+func (r *holderResolver) Balance() BigInt          { return newBigInt(r.h.Balance) }
+func (r *holderResolver) SettledBalance() BigInt   { return newBigInt(r.h.SettledBalance) }
+func (r *holderResolver) Payout() BigInt           { return newBigInt(r.h.Payout) }
+func (r *holderResolver) ExercisedOptions() BigInt { return newBigInt(r.h.ExercisedOptions) }
+func (r *holderResolver) LatestBlock() Uint64      { return Uint64(r.h.LatestBlock) }
+
+func (r *holderResolver) Round() (*roundResolver, error) {
+	return resolveRound(r.db, r.h.RoundAddress)
+}
+
+type lpResolver struct {
+	db *db.DB
+	lp *models.LiquidityProviderState
+}
+
+func newLPResolver(database *db.DB, lp *models.LiquidityProviderState) *lpResolver {
+	return &lpResolver{db: database, lp: lp}
+}
+
+func (r *lpResolver) Address() string         { return r.lp.Address }
+func (r *lpResolver) VaultAddress() string    { return r.lp.VaultAddress }
+func (r *lpResolver) UnlockedBalance() BigInt { return newBigInt(r.lp.UnlockedBalance) }
+func (r *lpResolver) LockedBalance() BigInt   { return newBigInt(r.lp.LockedBalance) }
+func (r *lpResolver) StashedBalance() BigInt  { return newBigInt(r.lp.StashedBalance) }
+func (r *lpResolver) LatestBlock() Uint64     { return Uint64(r.lp.LatestBlock) }
+
+func (r *lpResolver) Vault() (*vaultResolver, error) {
+	return resolveVault(r.db, r.lp.VaultAddress)
+}
+
+func (r *lpResolver) QueuedLiquidity(args pageArgs) ([]*queuedResolver, error) {
+	page, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	queued, err := r.db.ListQueuedLiquidityForLPInVault(r.lp.VaultAddress, r.lp.Address, page)
+	if err != nil {
+		return nil, err
+	}
+	return wrapAll(r.db, queued, newQueuedResolver), nil
+}
+
+func (r *lpResolver) Actions(args pageArgs) ([]*lpActionResolver, error) {
+	page, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+	actions, err := r.db.ListLPActions(r.lp.VaultAddress, r.lp.Address, page)
+	if err != nil {
+		return nil, err
+	}
+	return wrapAll(r.db, actions, newLPActionResolver), nil
+}
+
+type lpActionResolver struct {
+	a *models.LPAction
+}
+
+func newLPActionResolver(_ *db.DB, action *models.LPAction) *lpActionResolver {
+	return &lpActionResolver{a: action}
+}
+
+func (r *lpActionResolver) Type() string            { return r.a.Type }
+func (r *lpActionResolver) Amount() BigInt          { return newBigInt(r.a.Amount) }
+func (r *lpActionResolver) VaultAddress() string    { return r.a.VaultAddress }
+func (r *lpActionResolver) LpAddress() string       { return r.a.LPAddress }
+func (r *lpActionResolver) BlockNumber() Uint64     { return Uint64(r.a.BlockNumber) }
+func (r *lpActionResolver) TransactionHash() string { return r.a.TransactionHash }
+func (r *lpActionResolver) Timestamp() Uint64       { return Uint64(r.a.Timestamp) }
+
+type queuedResolver struct {
+	db *db.DB
+	q  *models.QueuedLiquidity
+}
+
+func newQueuedResolver(database *db.DB, queued *models.QueuedLiquidity) *queuedResolver {
+	return &queuedResolver{db: database, q: queued}
+}
+
+func (r *queuedResolver) Address() string      { return r.q.Address }
+func (r *queuedResolver) RoundAddress() string { return r.q.RoundAddress }
+func (r *queuedResolver) Bps() BigInt          { return newBigInt(r.q.Bps) }
+func (r *queuedResolver) QueuedAmount() BigInt { return newBigInt(r.q.QueuedAmount) }
+
+func (r *queuedResolver) Round() (*roundResolver, error) {
+	return resolveRound(r.db, r.q.RoundAddress)
+}
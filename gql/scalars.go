@@ -0,0 +1,68 @@
+package gql
+
+import (
+	"encoding/json"
+	"fmt"
+	"junoplugin/models"
+	"math/big"
+	"strconv"
+)
+
+// BigInt is the GraphQL BigInt scalar, a decimal string on the wire
+type BigInt struct {
+	models.BigInt
+}
+
+func newBigInt(value models.BigInt) BigInt {
+	return BigInt{value}
+}
+
+func (BigInt) ImplementsGraphQLType(name string) bool {
+	return name == "BigInt"
+}
+
+func (b *BigInt) UnmarshalGraphQL(input interface{}) error {
+	switch v := input.(type) {
+	case string:
+		value, ok := new(big.Int).SetString(v, 10)
+		if !ok {
+			return fmt.Errorf("invalid BigInt %q", v)
+		}
+		b.Int = value
+	case int32:
+		b.Int = big.NewInt(int64(v))
+	default:
+		return fmt.Errorf("wrong type for BigInt: %T", input)
+	}
+	return nil
+}
+
+// Uint64 is the GraphQL Uint64 scalar, a JSON number on the wire
+type Uint64 uint64
+
+func (Uint64) ImplementsGraphQLType(name string) bool {
+	return name == "Uint64"
+}
+
+func (u *Uint64) UnmarshalGraphQL(input interface{}) error {
+	switch v := input.(type) {
+	case string:
+		value, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*u = Uint64(value)
+	case int32:
+		if v < 0 {
+			return fmt.Errorf("negative Uint64 %d", v)
+		}
+		*u = Uint64(v)
+	default:
+		return fmt.Errorf("wrong type for Uint64: %T", input)
+	}
+	return nil
+}
+
+func (u Uint64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(uint64(u))
+}
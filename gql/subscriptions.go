@@ -0,0 +1,128 @@
+package gql
+
+import (
+	"context"
+	"junoplugin/models"
+	"junoplugin/notify"
+)
+
+const subscriptionBuffer = 256
+
+// subscribe streams the listener's updates matching filter through wrap until
+// ctx is cancelled
+func subscribe[M any, R any](
+	ctx context.Context,
+	r *Resolver,
+	filter notify.Filter,
+	wrap func(*M) *R,
+) <-chan *R {
+	sub := r.listener.Subscribe(filter, subscriptionBuffer)
+	out := make(chan *R)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				record, ok := update.Data.(*M)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- wrap(record):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+func (r *Resolver) VaultUpdated(ctx context.Context, args struct{ Address *string }) (<-chan *vaultResolver, error) {
+	address, err := normalizeOptional(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	filter := notify.Filter{Kinds: []notify.Kind{notify.KindVault}, Vault: address}
+	return subscribe(ctx, r, filter, func(vault *models.VaultState) *vaultResolver {
+		return newVaultResolver(r.db, vault)
+	}), nil
+}
+
+func (r *Resolver) RoundUpdated(ctx context.Context, args struct {
+	Vault   *string
+	Address *string
+}) (<-chan *roundResolver, error) {
+	vault, err := normalizeOptional(args.Vault)
+	if err != nil {
+		return nil, err
+	}
+	address, err := normalizeOptional(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	filter := notify.Filter{Kinds: []notify.Kind{notify.KindOptionRound}, Vault: vault, Round: address}
+	return subscribe(ctx, r, filter, func(round *models.OptionRound) *roundResolver {
+		return newRoundResolver(r.db, round)
+	}), nil
+}
+
+func (r *Resolver) LiquidityProviderUpdated(ctx context.Context, args struct {
+	Vault   *string
+	Address *string
+}) (<-chan *lpResolver, error) {
+	vault, err := normalizeOptional(args.Vault)
+	if err != nil {
+		return nil, err
+	}
+	address, err := normalizeOptional(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	filter := notify.Filter{Kinds: []notify.Kind{notify.KindLP}, Vault: vault, Account: address}
+	return subscribe(ctx, r, filter, func(lp *models.LiquidityProviderState) *lpResolver {
+		return newLPResolver(r.db, lp)
+	}), nil
+}
+
+func (r *Resolver) OptionBuyerUpdated(ctx context.Context, args struct {
+	Round   *string
+	Address *string
+}) (<-chan *buyerResolver, error) {
+	round, err := normalizeOptional(args.Round)
+	if err != nil {
+		return nil, err
+	}
+	address, err := normalizeOptional(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	filter := notify.Filter{Kinds: []notify.Kind{notify.KindOptionBuyer}, Round: round, Account: address}
+	return subscribe(ctx, r, filter, func(buyer *models.OptionBuyer) *buyerResolver {
+		return newBuyerResolver(r.db, buyer)
+	}), nil
+}
+
+func (r *Resolver) BidUpdated(ctx context.Context, args struct {
+	Round *string
+	Buyer *string
+}) (<-chan *bidResolver, error) {
+	round, err := normalizeOptional(args.Round)
+	if err != nil {
+		return nil, err
+	}
+	buyer, err := normalizeOptional(args.Buyer)
+	if err != nil {
+		return nil, err
+	}
+	filter := notify.Filter{Kinds: []notify.Kind{notify.KindBid}, Round: round, Account: buyer}
+	return subscribe(ctx, r, filter, func(bid *models.Bid) *bidResolver {
+		return newBidResolver(r.db, bid)
+	}), nil
+}
@@ -0,0 +1,29 @@
+// Package hexutil normalizes the hex strings addresses and class hashes are
+// written in. It only depends on the standard library, so packages such as
+// config can use it without linking Juno's cgo core.
+package hexutil
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// NormalizeHexString parses a felt-sized hex string (with or without the 0x
+// prefix) and returns it in the same form adaptors.FeltToHexString produces,
+// so addresses coming from config or user input compare equal to indexed ones.
+func NormalizeHexString(hexString string) (string, error) {
+	trimmed := strings.TrimSpace(hexString)
+	trimmed = strings.TrimPrefix(strings.TrimPrefix(trimmed, "0x"), "0X")
+	if trimmed == "" {
+		return "", fmt.Errorf("empty hex string")
+	}
+	num, success := new(big.Int).SetString(trimmed, 16)
+	if !success || num.Sign() < 0 {
+		return "", fmt.Errorf("invalid hex string %q", hexString)
+	}
+	if num.BitLen() > 252 {
+		return "", fmt.Errorf("hex string %q does not fit in a felt", hexString)
+	}
+	return "0x" + num.Text(16), nil
+}
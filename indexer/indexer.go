@@ -0,0 +1,585 @@
+package indexer
+
+import (
+	"errors"
+	"fmt"
+	"junoplugin/adaptors"
+	"junoplugin/config"
+	"junoplugin/db"
+	"junoplugin/metrics"
+	"junoplugin/models"
+	"log"
+
+	"github.com/NethermindEth/juno/core"
+)
+
+// Indexer maps Pitchlake events onto the db package. It is shared by the
+// Juno plugin and the standalone commands replaying the raw event log.
+type Indexer struct {
+	registry          *adaptors.Registry
+	vaultAddressesMap map[string]trackedVault
+	roundAddressesMap map[string]*adaptors.JunoAdaptor
+	// eventAddresses are the addresses the event being processed started
+	// tracking, forgotten again by UndoEvent
+	eventAddresses []string
+	deployer       string
+	udcAddress     string
+	db             *db.DB
+	recordEvents   bool
+	retrier        Retrier
+}
+
+// trackedVault holds the adaptors decoding a vault's events and those of the
+// option rounds it deploys
+type trackedVault struct {
+	adaptor      *adaptors.JunoAdaptor
+	roundAdaptor *adaptors.JunoAdaptor
+}
+
+// New creates an Indexer; recordEvents controls whether applied events are
+// appended to the Events table, which replays from that table must disable.
+func New(database *db.DB, cfg *config.Config, recordEvents bool) (*Indexer, error) {
+	registry, err := adaptors.NewRegistry(cfg.VaultVersions(), cfg.OptionRoundClasses)
+	if err != nil {
+		return nil, err
+	}
+	return &Indexer{
+		registry:          registry,
+		vaultAddressesMap: make(map[string]trackedVault),
+		roundAddressesMap: make(map[string]*adaptors.JunoAdaptor),
+		deployer:          cfg.Deployer,
+		udcAddress:        cfg.UDCAddress,
+		db:                database,
+		recordEvents:      recordEvents,
+	}, nil
+}
+
+// LoadAddressMaps rebuilds the vault and round address maps from the committed state
+func (idx *Indexer) LoadAddressMaps() error {
+	idx.vaultAddressesMap = make(map[string]trackedVault)
+	idx.roundAddressesMap = make(map[string]*adaptors.JunoAdaptor)
+	vaults, err := idx.db.GetVaultClasses()
+	if err != nil {
+		return err
+	}
+
+	//Map
+	for _, vault := range vaults {
+		tracked, err := idx.trackVault(vault.ClassHash, vault.OptionRoundClassHash)
+		if err != nil {
+			return fmt.Errorf("vault %s: %w", vault.Address, err)
+		}
+		idx.vaultAddressesMap[vault.Address] = tracked
+		roundAddresses, err := idx.db.GetRoundAddressess(vault.Address)
+		if err != nil {
+			return err
+		}
+		//Round Address Map
+		for _, roundAddress := range *roundAddresses {
+			idx.roundAddressesMap[roundAddress] = tracked.roundAdaptor
+		}
+	}
+	return nil
+}
+
+// trackVault picks the adaptors of a vault from its class hashes
+func (idx *Indexer) trackVault(classHash, optionRoundClassHash string) (trackedVault, error) {
+	var adaptor *adaptors.JunoAdaptor
+	if classHash == "" {
+		// Indexed before class hashes were stored
+		adaptor, _ = adaptors.Version(adaptors.DefaultVersion)
+	} else {
+		var ok bool
+		if adaptor, ok = idx.registry.Vault(classHash); !ok {
+			return trackedVault{}, fmt.Errorf("class %s is not listed in vault_hash or vault_classes", classHash)
+		}
+	}
+	return trackedVault{
+		adaptor:      adaptor,
+		roundAdaptor: idx.registry.OptionRound(optionRoundClassHash, adaptor),
+	}, nil
+}
+
+// Names of the handlers an event can fail in, stored with quarantined events
+const (
+	HandlerUDC   = "processUDC"
+	HandlerVault = "processVaultEvent"
+	HandlerRound = "processRoundEvent"
+)
+
+// Handles reports whether the event comes from the UDC or from a tracked
+// vault or round, i.e. whether ProcessEvent does anything with it
+func (idx *Indexer) Handles(event *core.Event) bool {
+	fromAddress := event.From.String()
+	if fromAddress == idx.udcAddress {
+		return true
+	}
+	if _, exists := idx.vaultAddressesMap[fromAddress]; exists {
+		return true
+	}
+	_, exists := idx.roundAddressesMap[fromAddress]
+	return exists
+}
+
+// Handler names the handler ProcessEvent runs for the event, or "" if the
+// event is not handled
+func (idx *Indexer) Handler(event *core.Event) string {
+	fromAddress := event.From.String()
+	if fromAddress == idx.udcAddress {
+		return HandlerUDC
+	}
+	if _, exists := idx.vaultAddressesMap[fromAddress]; exists {
+		return HandlerVault
+	}
+	if _, exists := idx.roundAddressesMap[fromAddress]; exists {
+		return HandlerRound
+	}
+	return ""
+}
+
+// Quarantine stores an event of the block that failed with err in
+// Quarantined_Events, from where `pitchlakectl retry-quarantined` can run it
+// again
+func (idx *Indexer) Quarantine(block *core.Block, txIndex, index int, err error) error {
+	event := block.Receipts[txIndex].Events[index]
+	quarantined := adaptors.QuarantinedEventToModel(block, txIndex, index, idx.Handler(event), err)
+	return idx.db.CreateQuarantinedEvent(&quarantined)
+}
+
+// ProcessEvent applies a single event of the block if it comes from the UDC or
+// from a tracked vault or round. Events that do not match their ABI fail with
+// an *adaptors.DecodeError carrying the event's coordinates. Other events of
+// tracked contracts, such as upgrades or ownership transfers, are skipped.
+func (idx *Indexer) ProcessEvent(block *core.Block, txIndex, index int) error {
+	receipt := block.Receipts[txIndex]
+	event := receipt.Events[index]
+	fromAddress := event.From.String()
+	if !idx.Handles(event) {
+		return nil
+	}
+	idx.eventAddresses = idx.eventAddresses[:0]
+	if len(event.Keys) == 0 {
+		return locateDecodeError(&adaptors.DecodeError{Reason: "event has no selector key"}, block, txIndex, index)
+	}
+
+	var err error
+	if fromAddress == idx.udcAddress {
+		err = idx.processUDC(block, txIndex, index)
+	} else if _, exists := idx.vaultAddressesMap[fromAddress]; exists {
+		if !adaptors.EventKindOf(event).IsVault() {
+			skipEvent(block, txIndex, index)
+			return nil
+		}
+		//HashMap processing
+		err = idx.processVaultEvent(fromAddress, event, block.Number, block.Timestamp, receipt.TransactionHash.String())
+		if err == nil {
+			err = idx.recordEvent(block, txIndex, index, adaptors.EventKindOf(event).String())
+		}
+	} else if _, exists := idx.roundAddressesMap[fromAddress]; exists {
+		if !adaptors.EventKindOf(event).IsRound() {
+			skipEvent(block, txIndex, index)
+			return nil
+		}
+		err = idx.processRoundEvent(fromAddress, event, block.Number)
+		if err == nil {
+			err = idx.recordEvent(block, txIndex, index, adaptors.EventKindOf(event).String())
+		}
+	}
+	if err != nil {
+		if decodeErr := locateDecodeError(err, block, txIndex, index); decodeErr != nil {
+			return decodeErr
+		}
+		return fmt.Errorf("tx %s event %d from %s: %w", receipt.TransactionHash, index, fromAddress, err)
+	}
+	return nil
+}
+
+// UndoEvent stops tracking the vaults and rounds added by the last
+// ProcessEvent call, after its writes were rolled back to a savepoint
+func (idx *Indexer) UndoEvent() {
+	idx.forget(0)
+}
+
+// skipEvent logs an event of a tracked contract that is not a Pitchlake
+// event. It is neither applied nor quarantined, since no fix to the indexer
+// would make it apply.
+func skipEvent(block *core.Block, txIndex, index int) {
+	receipt := block.Receipts[txIndex]
+	event := receipt.Events[index]
+	log.Printf(
+		"Skipping event %s from %s in tx %s: not a Pitchlake event",
+		event.Keys[0],
+		event.From,
+		receipt.TransactionHash,
+	)
+}
+
+// locateDecodeError returns err's DecodeError with the event coordinates
+// filled in, unless an inner call already located it, or nil for other errors
+func locateDecodeError(err error, block *core.Block, txIndex, index int) *adaptors.DecodeError {
+	var decodeErr *adaptors.DecodeError
+	if !errors.As(err, &decodeErr) {
+		return nil
+	}
+	if decodeErr.TransactionHash == "" {
+		receipt := block.Receipts[txIndex]
+		decodeErr.BlockNumber = block.Number
+		decodeErr.TransactionHash = receipt.TransactionHash.String()
+		decodeErr.EventIndex = index
+		decodeErr.From = receipt.Events[index].From.String()
+	}
+	return decodeErr
+}
+
+// recordEvent appends an applied event to the raw event log
+func (idx *Indexer) recordEvent(block *core.Block, txIndex, index int, eventName string) error {
+	if !idx.recordEvents {
+		return nil
+	}
+	event := adaptors.EventToModel(block, txIndex, index, eventName)
+	return idx.db.CreateEvent(&event)
+}
+
+func (idx *Indexer) processUDC(block *core.Block, txIndex, index int) error {
+	events := block.Receipts[txIndex].Events
+	event := events[index]
+	blockNumber := block.Number
+	timestamp := block.Timestamp
+
+	if adaptors.EventKindOf(event) == adaptors.EventContractDeployed {
+		deployed, err := adaptors.DecodeContractDeployed(*event)
+		if err != nil {
+			return err
+		}
+		address := deployed.Address
+		//ClassHash and deployer filter, may use other filters here
+
+		adaptor, indexed := idx.registry.Vault(deployed.ClassHash)
+		if indexed && deployed.Deployer == idx.deployer {
+			fossilClientAddress, ethAddress, optionRoundClassHash, alpha, strikeLevel, roundTransitionDuration, auctionDuration, roundDuration, err := adaptor.ContractDeployed(*event)
+			if err != nil {
+				return err
+			}
+			vault := models.VaultState{
+				CurrentRound:          *models.NewBigInt("1"),
+				UnlockedBalance:       *models.NewBigInt("0"),
+				LockedBalance:         *models.NewBigInt("0"),
+				StashedBalance:        *models.NewBigInt("0"),
+				Address:               address,
+				ClassHash:             deployed.ClassHash,
+				LatestBlock:           blockNumber,
+				FossilClientAddress:   fossilClientAddress,
+				EthAddress:            ethAddress,
+				OptionRoundClassHash:  optionRoundClassHash,
+				Alpha:                 alpha,
+				StrikeLevel:           strikeLevel,
+				RoundTransitionPeriod: roundTransitionDuration,
+				AuctionDuration:       auctionDuration,
+				RoundDuration:         roundDuration,
+				DeploymentDate:        timestamp,
+			}
+			if err := idx.db.CreateVault(&vault); err != nil {
+				return err
+			}
+			idx.vaultAddressesMap[address] = trackedVault{
+				adaptor:      adaptor,
+				roundAdaptor: idx.registry.OptionRound(optionRoundClassHash, adaptor),
+			}
+			idx.eventAddresses = append(idx.eventAddresses, address)
+			if err := idx.processConstructorEvents(block, txIndex, index, address); err != nil {
+				return err
+			}
+			if err := idx.recordEvent(block, txIndex, index, "ContractDeployed"); err != nil {
+				return err
+			}
+		}
+
+	}
+	return nil
+}
+
+// processConstructorEvents applies the events a vault's constructor emitted
+// (the first OptionRoundDeployed) before the UDC emitted its ContractDeployed
+// at index. Events are decoded before anything is written, so one that fails
+// to decode is quarantined on its own and the vault stays deployed. Events a
+// replay is retrying go to the Retrier instead.
+func (idx *Indexer) processConstructorEvents(block *core.Block, txIndex, index int, address string) error {
+	receipt := block.Receipts[txIndex]
+	for i, constructorEvent := range receipt.Events[:index] {
+		if constructorEvent == nil || constructorEvent.From.String() != address {
+			continue
+		}
+		apply := func() error {
+			if len(constructorEvent.Keys) == 0 {
+				return &adaptors.DecodeError{Reason: "event has no selector key"}
+			}
+			return idx.processVaultEvent(address, constructorEvent, block.Number, block.Timestamp, receipt.TransactionHash.String())
+		}
+		retried, err := idx.retry(block, txIndex, i, apply)
+		if err != nil {
+			return err
+		}
+		if retried {
+			continue
+		}
+		if len(constructorEvent.Keys) > 0 && !adaptors.EventKindOf(constructorEvent).IsVault() {
+			skipEvent(block, txIndex, i)
+			continue
+		}
+		if err := apply(); err != nil {
+			decodeErr := locateDecodeError(err, block, txIndex, i)
+			if decodeErr == nil {
+				return err
+			}
+			log.Printf("Quarantined constructor event: %v", decodeErr)
+			if err := idx.Quarantine(block, txIndex, i, decodeErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := idx.recordEvent(block, txIndex, i, adaptors.EventKindOf(constructorEvent).String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (idx *Indexer) processVaultEvent(
+	vaultAddress string,
+	event *core.Event,
+	blockNumber uint64,
+	timestamp uint64,
+	txHash string,
+) error {
+
+	var err error
+	// action is the LP's entry in LP_Actions, for the events that have one
+	var action *models.LPAction
+	kind := adaptors.EventKindOf(event)
+	vault := idx.vaultAddressesMap[vaultAddress]
+	switch kind {
+	case adaptors.EventDeposit: //Add withdrawQueue and collect queue case based on event
+		lpAddress,
+			amount,
+			lpUnlocked,
+			vaultUnlocked,
+			decodeErr := vault.adaptor.DepositOrWithdraw(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+
+		err = idx.db.DepositIndex(vaultAddress, lpAddress, lpUnlocked, vaultUnlocked, blockNumber)
+		action = &models.LPAction{Type: models.LPActionDeposit, LPAddress: lpAddress, Amount: amount}
+		//Map the other parameters as well
+	case adaptors.EventWithdrawal:
+		lpAddress,
+			amount,
+			lpUnlocked,
+			vaultUnlocked,
+			decodeErr := vault.adaptor.DepositOrWithdraw(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+
+		err = idx.db.WithdrawIndex(vaultAddress, lpAddress, lpUnlocked, vaultUnlocked, blockNumber)
+		action = &models.LPAction{Type: models.LPActionWithdrawal, LPAddress: lpAddress, Amount: amount}
+	case adaptors.EventWithdrawalQueued:
+		lpAddress,
+			bps,
+			roundId,
+			accountQueuedBefore,
+			accountQueuedNow,
+			vaultQueuedNow,
+			decodeErr := vault.adaptor.WithdrawalQueued(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+
+		err = idx.db.WithdrawalQueuedIndex(
+			lpAddress,
+			vaultAddress,
+			roundId,
+			bps,
+			accountQueuedBefore,
+			accountQueuedNow,
+			vaultQueuedNow,
+		)
+		action = &models.LPAction{Type: models.LPActionQueue, LPAddress: lpAddress, Amount: accountQueuedNow}
+
+	case adaptors.EventStashWithdrawn:
+		lpAddress, amount, vaultStashed, decodeErr := vault.adaptor.StashWithdrawn(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		err = idx.db.StashWithdrawnIndex(
+			vaultAddress,
+			lpAddress,
+			amount,
+			vaultStashed,
+			blockNumber,
+		)
+		action = &models.LPAction{Type: models.LPActionStash, LPAddress: lpAddress, Amount: amount}
+	case adaptors.EventOptionRoundDeployed:
+
+		optionRound, decodeErr := vault.adaptor.RoundDeployed(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		optionRound.DeploymentDate = timestamp
+		err = idx.db.RoundDeployedIndex(optionRound)
+		if err == nil {
+			idx.roundAddressesMap[optionRound.Address] = vault.roundAdaptor
+			idx.eventAddresses = append(idx.eventAddresses, optionRound.Address)
+		}
+	case adaptors.EventL1RequestFulfilled:
+		request, decodeErr := vault.adaptor.L1RequestFulfilled(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		request.FulfilledBlock = blockNumber
+		request.FulfilledAt = timestamp
+		err = idx.db.L1RequestFulfilledIndex(request)
+	}
+	if err != nil {
+		return err
+	}
+	if action != nil {
+		action.VaultAddress = vaultAddress
+		action.BlockNumber = blockNumber
+		action.TransactionHash = txHash
+		action.Timestamp = timestamp
+		return idx.db.CreateLPAction(action)
+	}
+	return nil
+}
+
+// recordRoundMetrics stores the performance of a round that just settled,
+// annualized over the vault's round duration
+func (idx *Indexer) recordRoundMetrics(
+	round models.OptionRound,
+	settlementPrice, payoutPerOption models.BigInt,
+	blockNumber uint64,
+) error {
+	vault, err := idx.db.GetVaultByAddress(round.VaultAddress)
+	if err != nil {
+		return err
+	}
+	round.SettlementPrice = settlementPrice
+	round.PayoutPerOption = payoutPerOption
+	roundMetrics := metrics.ForRound(round, vault.RoundDuration, blockNumber)
+	return idx.db.UpsertRoundMetrics(&roundMetrics)
+}
+
+func (idx *Indexer) processRoundEvent(
+	roundAddress string,
+	event *core.Event,
+	blockNumber uint64,
+) error {
+	kind := adaptors.EventKindOf(event)
+	prevStateOptionRound, err := idx.db.GetOptionRoundByAddress(roundAddress)
+	if err != nil {
+		return err
+	}
+	adaptor := idx.roundAddressesMap[roundAddress]
+	switch kind {
+	case adaptors.EventPricingDataSet:
+		strikePrice, capLevel, reservePrice, decodeErr := adaptor.PricingDataSet(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		err = idx.db.PricingDataSetIndex(roundAddress, strikePrice, capLevel, reservePrice)
+	case adaptors.EventAuctionStarted:
+		availableOptions, startingLiquidity, decodeErr := adaptor.AuctionStarted(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		err = idx.db.AuctionStartedIndex(
+			prevStateOptionRound.VaultAddress,
+			roundAddress,
+			blockNumber,
+			availableOptions,
+			startingLiquidity,
+		)
+
+	case adaptors.EventAuctionEnded:
+		optionsSold,
+			clearingPrice,
+			unsoldLiquidity,
+			clearingNonce,
+			premiums,
+			decodeErr := adaptor.AuctionEnded(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+
+		err = idx.db.AuctionEndedIndex(
+			*prevStateOptionRound,
+			roundAddress,
+			blockNumber,
+			clearingNonce,
+			optionsSold,
+			clearingPrice,
+			premiums,
+			unsoldLiquidity,
+		)
+	case adaptors.EventOptionRoundSettled:
+		settlementPrice, payoutPerOption, decodeErr := adaptor.RoundSettled(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		if err := idx.db.RoundSettledIndex(
+			*prevStateOptionRound,
+			roundAddress,
+			blockNumber,
+			settlementPrice,
+			prevStateOptionRound.SoldOptions,
+			payoutPerOption,
+		); err != nil {
+			return err
+		}
+		err = idx.recordRoundMetrics(*prevStateOptionRound, settlementPrice, payoutPerOption, blockNumber)
+	case adaptors.EventBidPlaced:
+		bid, buyer, decodeErr := adaptor.BidPlaced(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		err = idx.db.BidPlacedIndex(bid, buyer)
+	case adaptors.EventBidUpdated:
+		bidId, price, _, treeNonceNew, decodeErr := adaptor.BidUpdated(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		err = idx.db.BidUpdatedIndex(event.From.String(), bidId, price, treeNonceNew)
+	case adaptors.EventOptionsMinted:
+		buyerAddress, mintedAmount, decodeErr := adaptor.OptionsMinted(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		err = idx.db.OptionsMintedIndex(roundAddress, buyerAddress, mintedAmount)
+	case adaptors.EventOptionsExercised:
+		buyerAddress, totalExercised, _, exercisedAmount, decodeErr := adaptor.OptionsExercised(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		err = idx.db.OptionsExercisedIndex(roundAddress, buyerAddress, totalExercised, exercisedAmount)
+	case adaptors.EventUnusedBidsRefunded:
+		buyerAddress, refundedAmount, decodeErr := adaptor.UnusedBidsRefunded(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		err = idx.db.UnusedBidsRefundedIndex(roundAddress, buyerAddress, refundedAmount, blockNumber)
+	case adaptors.EventTransfer:
+		from, to, value, decodeErr := adaptor.Transfer(*event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		err = idx.db.TransferIndex(*prevStateOptionRound, from, to, value, blockNumber)
+	case adaptors.EventApproval:
+		// Allowances of option tokens are not indexed
+	}
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package indexer
+
+import (
+	"errors"
+	"junoplugin/adaptors"
+	"junoplugin/models"
+	"testing"
+
+	"github.com/NethermindEth/juno/core"
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+// fakeRetrier retries the events at the listed coordinates of every block
+// and records what the indexer handed it
+type fakeRetrier struct {
+	retrying map[[2]int]models.QuarantinedEvent
+	calls    []retryCall
+}
+
+type retryCall struct {
+	id        uint64
+	eventName string
+	err       error
+}
+
+func (r *fakeRetrier) Retrying(block *core.Block, txIndex, index int) (models.QuarantinedEvent, bool) {
+	q, ok := r.retrying[[2]int{txIndex, index}]
+	if ok {
+		delete(r.retrying, [2]int{txIndex, index})
+	}
+	return q, ok
+}
+
+func (r *fakeRetrier) Retry(q models.QuarantinedEvent, eventName string, apply func() error) error {
+	r.calls = append(r.calls, retryCall{id: q.ID, eventName: eventName, err: apply()})
+	return nil
+}
+
+const (
+	vaultAddress = "0xa11"
+	udcAddress   = "0x4a64"
+)
+
+func feltU(v uint64) *felt.Felt {
+	return new(felt.Felt).SetUint64(v)
+}
+
+// deploymentBlock holds a transaction whose vault constructor emitted a
+// Deposit too short to decode before the UDC's ContractDeployed
+func deploymentBlock() *core.Block {
+	return &core.Block{
+		Header: &core.Header{Number: 7, Hash: feltU(0xb7), Timestamp: 1700000000},
+		Receipts: []*core.TransactionReceipt{{
+			TransactionHash: feltU(0x7a),
+			Events: []*core.Event{
+				{From: feltU(0xa11), Keys: []*felt.Felt{adaptors.Selector("Deposit"), feltU(0x1f)}, Data: []*felt.Felt{feltU(1)}},
+				{From: feltU(0x4a64), Keys: []*felt.Felt{adaptors.Selector("ContractDeployed")}},
+			},
+		}},
+	}
+}
+
+// newTestIndexer tracks the vault as processUDC does right before it applies
+// the constructor events. It has no database, so the tests fail loudly if an
+// event is quarantined or written instead of handed to the Retrier.
+func newTestIndexer(t *testing.T) *Indexer {
+	t.Helper()
+	adaptor, ok := adaptors.Version(adaptors.DefaultVersion)
+	if !ok {
+		t.Fatalf("no %s adaptor", adaptors.DefaultVersion)
+	}
+	return &Indexer{
+		vaultAddressesMap: map[string]trackedVault{vaultAddress: {adaptor: adaptor, roundAdaptor: adaptor}},
+		roundAddressesMap: make(map[string]*adaptors.JunoAdaptor),
+		eventAddresses:    []string{vaultAddress},
+		udcAddress:        udcAddress,
+	}
+}
+
+func TestQuarantinedConstructorEventIsRetried(t *testing.T) {
+	idx := newTestIndexer(t)
+	retrier := &fakeRetrier{retrying: map[[2]int]models.QuarantinedEvent{{0, 0}: {ID: 3}}}
+	idx.SetRetrier(retrier)
+
+	if err := idx.processConstructorEvents(deploymentBlock(), 0, 1, vaultAddress); err != nil {
+		t.Fatalf("processConstructorEvents: %v", err)
+	}
+	if len(retrier.calls) != 1 {
+		t.Fatalf("Retry called %d times, want once", len(retrier.calls))
+	}
+	call := retrier.calls[0]
+	if call.id != 3 || call.eventName != "Deposit" {
+		t.Errorf("Retry(%d, %s), want Retry(3, Deposit)", call.id, call.eventName)
+	}
+	var decodeErr *adaptors.DecodeError
+	if !errors.As(call.err, &decodeErr) {
+		t.Errorf("retried constructor event failed with %v, want a *DecodeError", call.err)
+	}
+	if _, tracked := idx.vaultAddressesMap[vaultAddress]; !tracked {
+		t.Error("the failed retry stopped tracking the vault it was emitted by")
+	}
+}
+
+func TestRetryEventLeavesConstructorEventsToProcessUDC(t *testing.T) {
+	idx := newTestIndexer(t)
+	delete(idx.vaultAddressesMap, vaultAddress)
+	retrier := &fakeRetrier{retrying: map[[2]int]models.QuarantinedEvent{{0, 0}: {ID: 3}}}
+	idx.SetRetrier(retrier)
+
+	retried, err := idx.RetryEvent(deploymentBlock(), 0, 0)
+	if err != nil || retried {
+		t.Fatalf("RetryEvent = %v, %v before the vault is deployed, want false, nil", retried, err)
+	}
+	if len(retrier.calls) != 0 || len(retrier.retrying) != 1 {
+		t.Error("RetryEvent claimed a constructor event ahead of its ContractDeployed")
+	}
+}
+
+func TestFailedRetryOnlyForgetsItsOwnAddresses(t *testing.T) {
+	idx := newTestIndexer(t)
+	retrier := &fakeRetrier{retrying: map[[2]int]models.QuarantinedEvent{{0, 0}: {ID: 3}}}
+	idx.SetRetrier(retrier)
+
+	retried, err := idx.retry(deploymentBlock(), 0, 0, func() error {
+		idx.roundAddressesMap["0xb0"] = nil
+		idx.eventAddresses = append(idx.eventAddresses, "0xb0")
+		return errors.New("round rejected")
+	})
+	if err != nil || !retried {
+		t.Fatalf("retry = %v, %v, want true, nil", retried, err)
+	}
+	if _, tracked := idx.roundAddressesMap["0xb0"]; tracked {
+		t.Error("the round deployed by the failed retry is still tracked")
+	}
+	if _, tracked := idx.vaultAddressesMap[vaultAddress]; !tracked {
+		t.Error("the enclosing ContractDeployed's vault was forgotten")
+	}
+	if len(idx.eventAddresses) != 1 || idx.eventAddresses[0] != vaultAddress {
+		t.Errorf("eventAddresses = %v, want [%s]", idx.eventAddresses, vaultAddress)
+	}
+}
+
+func TestEventsOutsideThePitchlakeABIAreSkipped(t *testing.T) {
+	idx := newTestIndexer(t)
+	idx.roundAddressesMap["0xb0"] = idx.vaultAddressesMap[vaultAddress].roundAdaptor
+	block := &core.Block{
+		Header: &core.Header{Number: 8, Hash: feltU(0xb8)},
+		Receipts: []*core.TransactionReceipt{{
+			TransactionHash: feltU(0x8a),
+			Events: []*core.Event{
+				{From: feltU(0xa11), Keys: []*felt.Felt{adaptors.Selector("Upgraded")}, Data: []*felt.Felt{feltU(0xc1a55)}},
+				{From: feltU(0xb0), Keys: []*felt.Felt{adaptors.Selector("OwnershipTransferred"), feltU(1), feltU(2)}},
+			},
+		}},
+	}
+	for index := range block.Receipts[0].Events {
+		if err := idx.ProcessEvent(block, 0, index); err != nil {
+			t.Errorf("ProcessEvent(event %d) = %v, want it skipped", index, err)
+		}
+	}
+}
+
+func TestUnknownConstructorEventsAreSkipped(t *testing.T) {
+	idx := newTestIndexer(t)
+	block := deploymentBlock()
+	block.Receipts[0].Events[0].Keys = []*felt.Felt{adaptors.Selector("OwnershipTransferred"), feltU(0), feltU(1)}
+
+	if err := idx.processConstructorEvents(block, 0, 1, vaultAddress); err != nil {
+		t.Fatalf("processConstructorEvents = %v, want the event skipped", err)
+	}
+}
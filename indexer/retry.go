@@ -0,0 +1,75 @@
+package indexer
+
+import (
+	"junoplugin/adaptors"
+	"junoplugin/models"
+
+	"github.com/NethermindEth/juno/core"
+)
+
+// Retrier gives the quarantined events a replay runs again their
+// bookkeeping, so they are applied at their original place in the block
+// whether the replay reaches them directly or through the ContractDeployed
+// of the vault whose constructor emitted them.
+type Retrier interface {
+	// Retrying returns the quarantined event at the coordinates, if it is
+	// being retried and has not been attempted yet
+	Retrying(block *core.Block, txIndex, index int) (models.QuarantinedEvent, bool)
+	// Retry runs apply for q and records the outcome: an applied event
+	// joins Events under eventName, a failing one is rolled back and keeps
+	// its quarantine row. Only errors that should abort the replay are
+	// returned.
+	Retry(q models.QuarantinedEvent, eventName string, apply func() error) error
+}
+
+// SetRetrier makes the indexer hand the events r is retrying to it instead
+// of applying or quarantining them itself
+func (idx *Indexer) SetRetrier(r Retrier) {
+	idx.retrier = r
+}
+
+// RetryEvent hands the event to the Retrier if it is being retried,
+// reporting whether it was. Events from contracts that are not tracked yet
+// are left alone: a vault's constructor events are retried by the
+// ContractDeployed that follows them.
+func (idx *Indexer) RetryEvent(block *core.Block, txIndex, index int) (bool, error) {
+	if !idx.Handles(block.Receipts[txIndex].Events[index]) {
+		return false, nil
+	}
+	idx.eventAddresses = idx.eventAddresses[:0]
+	return idx.retry(block, txIndex, index, func() error {
+		return idx.ProcessEvent(block, txIndex, index)
+	})
+}
+
+// retry runs apply through the Retrier if the event is being retried. The
+// vaults and rounds apply starts tracking are forgotten again when it fails,
+// leaving those of the enclosing event alone.
+func (idx *Indexer) retry(block *core.Block, txIndex, index int, apply func() error) (bool, error) {
+	if idx.retrier == nil {
+		return false, nil
+	}
+	q, ok := idx.retrier.Retrying(block, txIndex, index)
+	if !ok {
+		return false, nil
+	}
+	event := block.Receipts[txIndex].Events[index]
+	tracked := len(idx.eventAddresses)
+	return true, idx.retrier.Retry(q, adaptors.EventKindOf(event).String(), func() error {
+		err := apply()
+		if err != nil {
+			idx.forget(tracked)
+		}
+		return err
+	})
+}
+
+// forget stops tracking the addresses the current event added after the
+// first tracked ones
+func (idx *Indexer) forget(tracked int) {
+	for _, address := range idx.eventAddresses[tracked:] {
+		delete(idx.vaultAddressesMap, address)
+		delete(idx.roundAddressesMap, address)
+	}
+	idx.eventAddresses = idx.eventAddresses[:tracked]
+}
@@ -0,0 +1,118 @@
+// Package metrics derives vault performance figures from settled option
+// rounds: what the LPs earned in premiums against what they paid out.
+package metrics
+
+import (
+	"junoplugin/models"
+	"math"
+	"math/big"
+)
+
+const secondsPerYear = 365 * 24 * 60 * 60
+
+// ForRound computes the metrics of a round at settlement. roundDuration is
+// the vault's option duration in seconds, used to annualize the return.
+// Ratios whose denominator is zero are reported as 0: a round without
+// starting liquidity has no return or premium ratio, and one without
+// premiums has no loss ratio.
+func ForRound(round models.OptionRound, roundDuration, settledBlock uint64) models.RoundMetrics {
+	totalPayout := new(big.Int).Mul(orZero(round.SoldOptions), orZero(round.PayoutPerOption))
+	premiums := orZero(round.Premiums)
+	startingLiquidity := orZero(round.StartingLiquidity)
+	profit := new(big.Int).Sub(premiums, totalPayout)
+
+	roundReturn := ratio(profit, startingLiquidity)
+	return models.RoundMetrics{
+		RoundAddress:      round.Address,
+		VaultAddress:      round.VaultAddress,
+		RoundID:           round.RoundID,
+		StartingLiquidity: models.BigInt{Int: new(big.Int).Set(startingLiquidity)},
+		Premiums:          models.BigInt{Int: new(big.Int).Set(premiums)},
+		TotalPayout:       models.BigInt{Int: totalPayout},
+		RoundReturn:       roundReturn,
+		AnnualizedYield:   annualize(roundReturn, roundDuration),
+		PremiumRatio:      ratio(premiums, startingLiquidity),
+		LossRatio:         ratio(totalPayout, premiums),
+		RoundDuration:     roundDuration,
+		SettledBlock:      settledBlock,
+	}
+}
+
+// ForVault aggregates the metrics of a vault's rounds. Liquidity-weighted
+// figures sum the rounds' amounts before dividing, so small rounds do not
+// skew them.
+func ForVault(vaultAddress string, rounds []models.RoundMetrics) models.VaultMetrics {
+	aggregate := models.VaultMetrics{
+		VaultAddress:      vaultAddress,
+		Rounds:            len(rounds),
+		StartingLiquidity: models.BigInt{Int: new(big.Int)},
+		Premiums:          models.BigInt{Int: new(big.Int)},
+		TotalPayout:       models.BigInt{Int: new(big.Int)},
+	}
+	if len(rounds) == 0 {
+		return aggregate
+	}
+	returns := make([]float64, len(rounds))
+	yields := make([]float64, len(rounds))
+	for i, round := range rounds {
+		aggregate.StartingLiquidity.Add(aggregate.StartingLiquidity.Int, orZero(round.StartingLiquidity))
+		aggregate.Premiums.Add(aggregate.Premiums.Int, orZero(round.Premiums))
+		aggregate.TotalPayout.Add(aggregate.TotalPayout.Int, orZero(round.TotalPayout))
+		returns[i] = round.RoundReturn
+		yields[i] = round.AnnualizedYield
+	}
+	aggregate.AverageRoundReturn = mean(returns)
+	aggregate.AverageAnnualizedYield = mean(yields)
+	profit := new(big.Int).Sub(aggregate.Premiums.Int, aggregate.TotalPayout.Int)
+	aggregate.CumulativeReturn = ratio(profit, aggregate.StartingLiquidity.Int)
+	aggregate.PremiumRatio = ratio(aggregate.Premiums.Int, aggregate.StartingLiquidity.Int)
+	aggregate.LossRatio = ratio(aggregate.TotalPayout.Int, aggregate.Premiums.Int)
+	return aggregate
+}
+
+// annualize compounds a round's return over a year of back-to-back rounds.
+// A total loss stays at -1 rather than going undefined, a zero duration
+// yields 0 and a yield too large for a float64 is capped, since neither
+// JSON nor GraphQL can carry infinities.
+func annualize(roundReturn float64, roundDuration uint64) float64 {
+	if roundDuration == 0 {
+		return 0
+	}
+	if roundReturn <= -1 {
+		return -1
+	}
+	yield := math.Pow(1+roundReturn, secondsPerYear/float64(roundDuration)) - 1
+	if math.IsInf(yield, 1) {
+		return math.MaxFloat64
+	}
+	return yield
+}
+
+// mean averages values, dividing before summing so yields capped at the
+// float64 limit do not overflow
+func mean(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value / float64(len(values))
+	}
+	if math.IsInf(sum, 1) {
+		return math.MaxFloat64
+	}
+	return sum
+}
+
+// ratio divides two token amounts, 0 when the denominator is 0
+func ratio(numerator, denominator *big.Int) float64 {
+	if denominator.Sign() == 0 {
+		return 0
+	}
+	value, _ := new(big.Rat).SetFrac(numerator, denominator).Float64()
+	return value
+}
+
+func orZero(value models.BigInt) *big.Int {
+	if value.Int == nil {
+		return new(big.Int)
+	}
+	return value.Int
+}
@@ -0,0 +1,268 @@
+package metrics
+
+import (
+	"encoding/json"
+	"junoplugin/models"
+	"math"
+	"math/big"
+	"testing"
+)
+
+const (
+	day  = 24 * 60 * 60
+	week = 7 * day
+)
+
+func amount(v int64) models.BigInt {
+	return models.BigInt{Int: big.NewInt(v)}
+}
+
+func near(got, want float64) bool {
+	if want == 0 {
+		return math.Abs(got) < 1e-12
+	}
+	return math.Abs(got-want) <= 1e-9*math.Abs(want)
+}
+
+func TestForRound(t *testing.T) {
+	tests := []struct {
+		name          string
+		round         models.OptionRound
+		roundDuration uint64
+		totalPayout   int64
+		roundReturn   float64
+		yield         float64
+		premiumRatio  float64
+		lossRatio     float64
+	}{
+		{
+			name: "profitable round",
+			round: models.OptionRound{
+				StartingLiquidity: amount(1000),
+				Premiums:          amount(50),
+				SoldOptions:       amount(10),
+				PayoutPerOption:   amount(2),
+			},
+			roundDuration: week,
+			totalPayout:   20,
+			roundReturn:   0.03,
+			yield:         math.Pow(1.03, 365.0/7) - 1,
+			premiumRatio:  0.05,
+			lossRatio:     0.4,
+		},
+		{
+			name: "out of the money round keeps every premium",
+			round: models.OptionRound{
+				StartingLiquidity: amount(2000),
+				Premiums:          amount(100),
+				SoldOptions:       amount(10),
+				PayoutPerOption:   amount(0),
+			},
+			roundDuration: day,
+			roundReturn:   0.05,
+			yield:         math.Pow(1.05, 365) - 1,
+			premiumRatio:  0.05,
+			lossRatio:     0,
+		},
+		{
+			name: "payout larger than the premiums",
+			round: models.OptionRound{
+				StartingLiquidity: amount(1000),
+				Premiums:          amount(50),
+				SoldOptions:       amount(10),
+				PayoutPerOption:   amount(15),
+			},
+			roundDuration: week,
+			totalPayout:   150,
+			roundReturn:   -0.1,
+			yield:         math.Pow(0.9, 365.0/7) - 1,
+			premiumRatio:  0.05,
+			lossRatio:     3,
+		},
+		{
+			name: "total loss is clamped to -1",
+			round: models.OptionRound{
+				StartingLiquidity: amount(100),
+				Premiums:          amount(10),
+				SoldOptions:       amount(10),
+				PayoutPerOption:   amount(11),
+			},
+			roundDuration: week,
+			totalPayout:   110,
+			roundReturn:   -1,
+			yield:         -1,
+			premiumRatio:  0.1,
+			lossRatio:     11,
+		},
+		{
+			name: "zero round duration",
+			round: models.OptionRound{
+				StartingLiquidity: amount(1000),
+				Premiums:          amount(50),
+				SoldOptions:       amount(10),
+				PayoutPerOption:   amount(2),
+			},
+			roundDuration: 0,
+			totalPayout:   20,
+			roundReturn:   0.03,
+			yield:         0,
+			premiumRatio:  0.05,
+			lossRatio:     0.4,
+		},
+		{
+			name: "zero starting liquidity",
+			round: models.OptionRound{
+				StartingLiquidity: amount(0),
+				Premiums:          amount(0),
+				SoldOptions:       amount(0),
+				PayoutPerOption:   amount(0),
+			},
+			roundDuration: week,
+		},
+		{
+			name: "zero premiums with a payout",
+			round: models.OptionRound{
+				StartingLiquidity: amount(1000),
+				Premiums:          amount(0),
+				SoldOptions:       amount(5),
+				PayoutPerOption:   amount(4),
+			},
+			roundDuration: week,
+			totalPayout:   20,
+			roundReturn:   -0.02,
+			yield:         math.Pow(0.98, 365.0/7) - 1,
+			premiumRatio:  0,
+			lossRatio:     0,
+		},
+		{
+			name:          "unset amounts",
+			round:         models.OptionRound{},
+			roundDuration: week,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.round.Address = "0x1"
+			tt.round.VaultAddress = "0x2"
+			tt.round.RoundID = amount(3)
+			got := ForRound(tt.round, tt.roundDuration, 42)
+			if got.RoundAddress != "0x1" || got.VaultAddress != "0x2" || got.RoundID.Int64() != 3 {
+				t.Errorf("ForRound keys = %s %s %v", got.RoundAddress, got.VaultAddress, got.RoundID)
+			}
+			if got.RoundDuration != tt.roundDuration || got.SettledBlock != 42 {
+				t.Errorf("RoundDuration, SettledBlock = %d, %d", got.RoundDuration, got.SettledBlock)
+			}
+			if got.TotalPayout.Int64() != tt.totalPayout {
+				t.Errorf("TotalPayout = %v, want %d", got.TotalPayout, tt.totalPayout)
+			}
+			if !near(got.RoundReturn, tt.roundReturn) {
+				t.Errorf("RoundReturn = %v, want %v", got.RoundReturn, tt.roundReturn)
+			}
+			if !near(got.AnnualizedYield, tt.yield) {
+				t.Errorf("AnnualizedYield = %v, want %v", got.AnnualizedYield, tt.yield)
+			}
+			if !near(got.PremiumRatio, tt.premiumRatio) {
+				t.Errorf("PremiumRatio = %v, want %v", got.PremiumRatio, tt.premiumRatio)
+			}
+			if !near(got.LossRatio, tt.lossRatio) {
+				t.Errorf("LossRatio = %v, want %v", got.LossRatio, tt.lossRatio)
+			}
+		})
+	}
+}
+
+func TestAnnualizeStaysFinite(t *testing.T) {
+	tests := []struct {
+		name          string
+		roundReturn   float64
+		roundDuration uint64
+		want          float64
+	}{
+		{"one second rounds overflow", 0.5, 1, math.MaxFloat64},
+		{"no return", 0, week, 0},
+		{"return over a full year", 0.1, secondsPerYear, 0.1},
+		{"loss beyond the liquidity", -3, week, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := annualize(tt.roundReturn, tt.roundDuration)
+			if !near(got, tt.want) {
+				t.Errorf("annualize(%v, %d) = %v, want %v", tt.roundReturn, tt.roundDuration, got, tt.want)
+			}
+			if _, err := json.Marshal(got); err != nil {
+				t.Errorf("annualize(%v, %d) cannot be encoded: %v", tt.roundReturn, tt.roundDuration, err)
+			}
+		})
+	}
+}
+
+func TestForVault(t *testing.T) {
+	rounds := []models.RoundMetrics{
+		ForRound(models.OptionRound{
+			StartingLiquidity: amount(1000),
+			Premiums:          amount(50),
+			SoldOptions:       amount(10),
+			PayoutPerOption:   amount(2),
+		}, week, 10),
+		ForRound(models.OptionRound{
+			StartingLiquidity: amount(9000),
+			Premiums:          amount(90),
+			SoldOptions:       amount(10),
+			PayoutPerOption:   amount(18),
+		}, week, 20),
+	}
+	got := ForVault("0xabc", rounds)
+	if got.VaultAddress != "0xabc" || got.Rounds != 2 {
+		t.Errorf("ForVault = %s with %d rounds", got.VaultAddress, got.Rounds)
+	}
+	if got.StartingLiquidity.Int64() != 10000 || got.Premiums.Int64() != 140 || got.TotalPayout.Int64() != 200 {
+		t.Errorf("totals = %v %v %v, want 10000 140 200", got.StartingLiquidity, got.Premiums, got.TotalPayout)
+	}
+	// Plain averages of the rounds: 0.03 and -0.01
+	if !near(got.AverageRoundReturn, 0.01) {
+		t.Errorf("AverageRoundReturn = %v, want 0.01", got.AverageRoundReturn)
+	}
+	if want := (rounds[0].AnnualizedYield + rounds[1].AnnualizedYield) / 2; !near(got.AverageAnnualizedYield, want) {
+		t.Errorf("AverageAnnualizedYield = %v, want %v", got.AverageAnnualizedYield, want)
+	}
+	// Liquidity weighted: the larger losing round dominates
+	if !near(got.CumulativeReturn, -0.006) {
+		t.Errorf("CumulativeReturn = %v, want -0.006", got.CumulativeReturn)
+	}
+	if !near(got.PremiumRatio, 0.014) {
+		t.Errorf("PremiumRatio = %v, want 0.014", got.PremiumRatio)
+	}
+	if !near(got.LossRatio, 200.0/140) {
+		t.Errorf("LossRatio = %v, want %v", got.LossRatio, 200.0/140)
+	}
+}
+
+func TestForVaultEdgeCases(t *testing.T) {
+	t.Run("no settled rounds", func(t *testing.T) {
+		got := ForVault("0xabc", nil)
+		if got.Rounds != 0 || got.StartingLiquidity.Sign() != 0 || got.AverageRoundReturn != 0 || got.LossRatio != 0 {
+			t.Errorf("ForVault(nil) = %+v, want zero metrics", got)
+		}
+		if _, err := json.Marshal(got); err != nil {
+			t.Errorf("ForVault(nil) cannot be encoded: %v", err)
+		}
+	})
+	t.Run("rounds without liquidity or premiums", func(t *testing.T) {
+		got := ForVault("0xabc", []models.RoundMetrics{
+			ForRound(models.OptionRound{}, week, 1),
+			ForRound(models.OptionRound{}, 0, 2),
+		})
+		if got.CumulativeReturn != 0 || got.PremiumRatio != 0 || got.LossRatio != 0 {
+			t.Errorf("ForVault = %+v, want zero ratios", got)
+		}
+	})
+	t.Run("capped yields do not overflow the average", func(t *testing.T) {
+		got := ForVault("0xabc", []models.RoundMetrics{
+			{AnnualizedYield: math.MaxFloat64},
+			{AnnualizedYield: math.MaxFloat64},
+		})
+		if math.IsInf(got.AverageAnnualizedYield, 0) || got.AverageAnnualizedYield <= 0 {
+			t.Errorf("AverageAnnualizedYield = %v, want a finite positive value", got.AverageAnnualizedYield)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"math/big"
 )
@@ -49,98 +50,312 @@ func (b BigInt) Value() (driver.Value, error) {
 	return b.Int.String(), nil
 }
 
+// MarshalJSON encodes the value as a decimal string, since u256 amounts do not
+// fit in a JSON number without losing precision in JavaScript clients
+func (b BigInt) MarshalJSON() ([]byte, error) {
+	if b.Int == nil {
+		return []byte(`"0"`), nil
+	}
+	return json.Marshal(b.Int.String())
+}
+
+// UnmarshalJSON accepts a decimal string or a JSON number, the latter being how
+// row_to_json encodes numeric columns in notify payloads
+func (b *BigInt) UnmarshalJSON(data []byte) error {
+	if b.Int == nil {
+		b.Int = new(big.Int)
+	}
+	raw := string(data)
+	if raw == "null" {
+		b.Int.SetInt64(0)
+		return nil
+	}
+	if len(raw) >= 2 && raw[0] == '"' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	}
+	if _, ok := b.Int.SetString(raw, 10); !ok {
+		return fmt.Errorf("failed to unmarshal BigInt: invalid number %s", data)
+	}
+	return nil
+}
+
+// StringList stores a list of hex strings (event keys and data) as a JSON array
+type StringList []string
+
+// Scan implements the sql.Scanner interface for StringList
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	case []byte:
+		return json.Unmarshal(v, l)
+	default:
+		return fmt.Errorf("unsupported scan type for StringList: %T", value)
+	}
+}
+
+// Value implements the driver.Valuer interface for StringList
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
 type Vault struct {
-	BlockNumber     uint64 `gorm:"column:block_number;type:numeric(78,0);not null"`
-	UnlockedBalance BigInt `gorm:"column:unlocked_balance;not null"`
-	LockedBalance   BigInt `gorm:"column:locked_balance;not null"`
-	StashedBalance  BigInt `gorm:"column:stashed_balance;not null"`
+	Address         string `gorm:"column:address;not null" json:"address"`
+	BlockNumber     uint64 `gorm:"column:block_number;type:numeric(78,0);not null" json:"block_number"`
+	UnlockedBalance BigInt `gorm:"column:unlocked_balance;not null" json:"unlocked_balance"`
+	LockedBalance   BigInt `gorm:"column:locked_balance;not null" json:"locked_balance"`
+	StashedBalance  BigInt `gorm:"column:stashed_balance;not null" json:"stashed_balance"`
 }
 
 type LiquidityProvider struct {
-	VaultAddress    string `gorm:"column:vault_address;not null"`
-	Address         string `gorm:"column:address;not null"`
-	UnlockedBalance BigInt `gorm:"column:unlocked_balance;not null"`
-	LockedBalance   BigInt `gorm:"column:locked_balance;not null"`
-	StashedBalance  BigInt `gorm:"column:stashed_balance;not null"`
-	BlockNumber     uint64 `gorm:"column:block_number;not null"`
+	VaultAddress    string `gorm:"column:vault_address;not null" json:"vault_address"`
+	Address         string `gorm:"column:address;not null" json:"address"`
+	UnlockedBalance BigInt `gorm:"column:unlocked_balance;not null" json:"unlocked_balance"`
+	LockedBalance   BigInt `gorm:"column:locked_balance;not null" json:"locked_balance"`
+	StashedBalance  BigInt `gorm:"column:stashed_balance;not null" json:"stashed_balance"`
+	BlockNumber     uint64 `gorm:"column:block_number;not null" json:"block_number"`
 }
 
 type OptionBuyer struct {
-	Address string `gorm:"column:address;not null"`
+	Address string `gorm:"column:address;not null" json:"address"`
 	//Maybe this is not required and can be directly fetched as a view/index on the bids table
 	//Bids       string `gorm:"column:bids;type:jsonb"` // Store bids as JSON in PostgreSQL
-	RoundAddress      string `gorm:"column:round_address;not null"`
-	MintableOptions   BigInt `gorm:"column:mintable_options;"`
-	HasMinted         bool   `gorm:"column:has_minted;"`
-	RefundableOptions BigInt `gorm:"column:refundable_amount;"`
-	HasRefunded       bool   `gorm:"column:has_refunded;"`
+	RoundAddress     string `gorm:"column:round_address;not null" json:"round_address"`
+	MintableOptions  BigInt `gorm:"column:mintable_options;" json:"mintable_options"`
+	HasMinted        bool   `gorm:"column:has_minted;" json:"has_minted"`
+	RefundableAmount BigInt `gorm:"column:refundable_amount;" json:"refundable_amount"`
+	HasRefunded      bool   `gorm:"column:has_refunded;" json:"has_refunded"`
+	ExercisedOptions BigInt `gorm:"column:exercised_options;" json:"exercised_options"`
+	ExercisedAmount  BigInt `gorm:"column:exercised_amount;" json:"exercised_amount"`
+	MintedOptions    BigInt `gorm:"column:minted_options;" json:"minted_options"`
+	RefundedAmount   BigInt `gorm:"column:refunded_amount;" json:"refunded_amount"`
+	RefundedBlock    uint64 `gorm:"column:refunded_block;" json:"refunded_block"`
+	// RefundMismatch is set when RefundedAmount differs from RefundableAmount
+	RefundMismatch bool `gorm:"column:refund_mismatch;" json:"refund_mismatch"`
 }
 
 type OptionRound struct {
-	VaultAddress       string `gorm:"column:vault_address;"`
-	Address            string `gorm:"column:address"`
-	RoundID            BigInt `gorm:"column:round_id;"` // Store bids as JSON in PostgreSQL
-	CapLevel           BigInt `gorm:"column:cap_level"`
-	StartDate          uint64 `gorm:"column:start_date;"`
-	EndDate            uint64 `gorm:"column:end_date;"`
-	SettlementDate     uint64 `gorm:"column:settlement_date;"`
-	StartingLiquidity  BigInt `gorm:"column:starting_liquidity;"`
-	QueuedLiquidity    BigInt `gorm:"column:queued_liquidity;"`
-	RemainingLiquidity BigInt `gorm:"column:remaining_liquidity;"`
-	AvailableOptions   BigInt `gorm:"column:available_options;"`
-	SettlementPrice    BigInt `gorm:"column:settlement_price;"`
-	StrikePrice        BigInt `gorm:"column:strike_price;"`
-	UnsoldLiquidity    BigInt `gorm:"column:unsold_liquidity;"`
-	SoldOptions        BigInt `gorm:"column:sold_options;"`
-	ReservePrice       BigInt `gorm:"column:reserve_price"`
-	ClearingPrice      BigInt `gorm:"column:clearing_price"`
-	State              string `gorm:"column:state;"`
-	Premiums           BigInt `gorm:"column:premiums;"`
-	PayoutPerOption    BigInt `gorm:"column:payout_per_option;"`
-	DeploymentDate     uint64 `gorm:"column:deployment_date;"`
+	VaultAddress       string `gorm:"column:vault_address;" json:"vault_address"`
+	Address            string `gorm:"column:address" json:"address"`
+	RoundID            BigInt `gorm:"column:round_id;" json:"round_id"` // Store bids as JSON in PostgreSQL
+	CapLevel           BigInt `gorm:"column:cap_level" json:"cap_level"`
+	StartDate          uint64 `gorm:"column:start_date;" json:"start_date"`
+	EndDate            uint64 `gorm:"column:end_date;" json:"end_date"`
+	SettlementDate     uint64 `gorm:"column:settlement_date;" json:"settlement_date"`
+	StartingLiquidity  BigInt `gorm:"column:starting_liquidity;" json:"starting_liquidity"`
+	QueuedLiquidity    BigInt `gorm:"column:queued_liquidity;" json:"queued_liquidity"`
+	RemainingLiquidity BigInt `gorm:"column:remaining_liquidity;" json:"remaining_liquidity"`
+	AvailableOptions   BigInt `gorm:"column:available_options;" json:"available_options"`
+	SettlementPrice    BigInt `gorm:"column:settlement_price;" json:"settlement_price"`
+	StrikePrice        BigInt `gorm:"column:strike_price;" json:"strike_price"`
+	UnsoldLiquidity    BigInt `gorm:"column:unsold_liquidity;" json:"unsold_liquidity"`
+	SoldOptions        BigInt `gorm:"column:sold_options;" json:"sold_options"`
+	ReservePrice       BigInt `gorm:"column:reserve_price" json:"reserve_price"`
+	ClearingPrice      BigInt `gorm:"column:clearing_price" json:"clearing_price"`
+	State              string `gorm:"column:state;" json:"state"`
+	Premiums           BigInt `gorm:"column:premiums;" json:"premiums"`
+	PayoutPerOption    BigInt `gorm:"column:payout_per_option;" json:"payout_per_option"`
+	DeploymentDate     uint64 `gorm:"column:deployment_date;" json:"deployment_date"`
+	ExercisedOptions   BigInt `gorm:"column:exercised_options;" json:"exercised_options"`
+	ExercisedAmount    BigInt `gorm:"column:exercised_amount;" json:"exercised_amount"`
+	MintedOptions      BigInt `gorm:"column:minted_options;" json:"minted_options"`
 }
 
 type VaultState struct {
-	CurrentRound          BigInt `gorm:"column:current_round;not null;"`
-	CurrentRoundAddress   string `gorm:"column:current_round_address;"`
-	UnlockedBalance       BigInt `gorm:"column:unlocked_balance;"`
-	LockedBalance         BigInt `gorm:"column:locked_balance;"`
-	StashedBalance        BigInt `gorm:"column:stashed_balance;"`
-	Address               string `gorm:"column:address;not null;"`
-	LatestBlock           uint64 `gorm:"column:latest_block;"`
-	FossilClientAddress   string `gorm:"column:fossil_client_address;"`
-	EthAddress            string `gorm:"column:eth_address;"`
-	OptionRoundClassHash  string `gorm:"column:option_round_class_hash;"`
-	Alpha                 BigInt `gorm:"column:alpha;"`
-	StrikeLevel           BigInt `gorm:"column:strike_level;"`
-	RoundTransitionPeriod uint64 `gorm:"column:round_transition_period;"`
-	AuctionDuration       uint64 `gorm:"column:auction_duration;"`
-	RoundDuration         uint64 `gorm:"column:round_duration;"`
-	DeploymentDate        uint64 `gorm:"column:deployment_date;"`
+	CurrentRound          BigInt `gorm:"column:current_round;not null;" json:"current_round"`
+	CurrentRoundAddress   string `gorm:"column:current_round_address;" json:"current_round_address"`
+	UnlockedBalance       BigInt `gorm:"column:unlocked_balance;" json:"unlocked_balance"`
+	LockedBalance         BigInt `gorm:"column:locked_balance;" json:"locked_balance"`
+	StashedBalance        BigInt `gorm:"column:stashed_balance;" json:"stashed_balance"`
+	Address               string `gorm:"column:address;not null;" json:"address"`
+	ClassHash             string `gorm:"column:class_hash;" json:"class_hash"`
+	LatestBlock           uint64 `gorm:"column:latest_block;" json:"latest_block"`
+	FossilClientAddress   string `gorm:"column:fossil_client_address;" json:"fossil_client_address"`
+	EthAddress            string `gorm:"column:eth_address;" json:"eth_address"`
+	OptionRoundClassHash  string `gorm:"column:option_round_class_hash;" json:"option_round_class_hash"`
+	Alpha                 BigInt `gorm:"column:alpha;" json:"alpha"`
+	StrikeLevel           BigInt `gorm:"column:strike_level;" json:"strike_level"`
+	RoundTransitionPeriod uint64 `gorm:"column:round_transition_period;" json:"round_transition_period"`
+	AuctionDuration       uint64 `gorm:"column:auction_duration;" json:"auction_duration"`
+	RoundDuration         uint64 `gorm:"column:round_duration;" json:"round_duration"`
+	DeploymentDate        uint64 `gorm:"column:deployment_date;" json:"deployment_date"`
 }
 
 type LiquidityProviderState struct {
-	VaultAddress    string `gorm:"column:vault_address;not null"`
-	Address         string `gorm:"column:address;not null;primaryKey"`
-	UnlockedBalance BigInt `gorm:"column:unlocked_balance;not null"`
-	LockedBalance   BigInt `gorm:"column:locked_balance;not null"`
-	StashedBalance  BigInt `gorm:"column:stashed_balance;"`
-	LatestBlock     uint64 `gorm:"column:latest_block;"`
+	VaultAddress    string `gorm:"column:vault_address;not null" json:"vault_address"`
+	Address         string `gorm:"column:address;not null;primaryKey" json:"address"`
+	UnlockedBalance BigInt `gorm:"column:unlocked_balance;not null" json:"unlocked_balance"`
+	LockedBalance   BigInt `gorm:"column:locked_balance;not null" json:"locked_balance"`
+	StashedBalance  BigInt `gorm:"column:stashed_balance;" json:"stashed_balance"`
+	LatestBlock     uint64 `gorm:"column:latest_block;" json:"latest_block"`
 }
 
 type QueuedLiquidity struct {
-	Address      string `gorm:"column:address;not null"`
-	RoundAddress string `gorm:"column:round_address;not null"`
-	Bps          BigInt `gorm:"column:bps;not null"`
-	QueuedAmount BigInt `gorm:"column:queued_liquidity;not null"`
+	Address      string `gorm:"column:address;not null" json:"address"`
+	RoundAddress string `gorm:"column:round_address;not null" json:"round_address"`
+	Bps          BigInt `gorm:"column:bps;not null" json:"bps"`
+	QueuedAmount BigInt `gorm:"column:queued_liquidity;not null" json:"queued_liquidity"`
 }
 type Bid struct {
-	BuyerAddress string `gorm:"column:buyer_address;not null"`
-	RoundAddress string `gorm:"column:round_address;not null"`
-	BidID        string `gorm:"column:bid_id;not null"`
-	TreeNonce    uint64 `gorm:"column:tree_nonce;not null"`
-	Amount       BigInt `gorm:"column:amount;not null"`
-	Price        BigInt `gorm:"column:price;not null"`
+	BuyerAddress string `gorm:"column:buyer_address;not null" json:"buyer_address"`
+	RoundAddress string `gorm:"column:round_address;not null" json:"round_address"`
+	BidID        string `gorm:"column:bid_id;not null" json:"bid_id"`
+	TreeNonce    uint64 `gorm:"column:tree_nonce;not null" json:"tree_nonce"`
+	Amount       BigInt `gorm:"column:amount;not null" json:"amount"`
+	Price        BigInt `gorm:"column:price;not null" json:"price"`
+}
+
+// OptionHolder is an account's balance of a round's option tokens.
+// SettledBalance and Payout are set when the round settles, ExercisedOptions
+// counts the tokens burnt afterwards.
+type OptionHolder struct {
+	RoundAddress     string `gorm:"column:round_address;primaryKey" json:"round_address"`
+	Address          string `gorm:"column:address;primaryKey" json:"address"`
+	Balance          BigInt `gorm:"column:balance;not null" json:"balance"`
+	SettledBalance   BigInt `gorm:"column:settled_balance;not null" json:"settled_balance"`
+	Payout           BigInt `gorm:"column:payout;not null" json:"payout"`
+	ExercisedOptions BigInt `gorm:"column:exercised_options;not null" json:"exercised_options"`
+	LatestBlock      uint64 `gorm:"column:latest_block;not null" json:"latest_block"`
+}
+
+// LP action types
+const (
+	LPActionDeposit    = "deposit"
+	LPActionWithdrawal = "withdrawal"
+	LPActionStash      = "stash"
+	LPActionQueue      = "queue"
+)
+
+// LPAction is one entry of a liquidity provider's activity feed. For queued
+// withdrawals Amount is the liquidity the LP has queued after the action.
+type LPAction struct {
+	ID              uint64 `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Type            string `gorm:"column:type;not null" json:"type"`
+	Amount          BigInt `gorm:"column:amount;not null" json:"amount"`
+	VaultAddress    string `gorm:"column:vault_address;not null" json:"vault_address"`
+	LPAddress       string `gorm:"column:lp_address;not null" json:"lp_address"`
+	BlockNumber     uint64 `gorm:"column:block_number;not null" json:"block_number"`
+	TransactionHash string `gorm:"column:transaction_hash;not null" json:"transaction_hash"`
+	Timestamp       uint64 `gorm:"column:timestamp;not null" json:"timestamp"`
+}
+
+// RoundMetrics is the performance of a settled round for its LPs, see
+// package metrics for the definitions
+type RoundMetrics struct {
+	RoundAddress      string  `gorm:"column:round_address;primaryKey" json:"round_address"`
+	VaultAddress      string  `gorm:"column:vault_address;not null" json:"vault_address"`
+	RoundID           BigInt  `gorm:"column:round_id;not null" json:"round_id"`
+	StartingLiquidity BigInt  `gorm:"column:starting_liquidity;not null" json:"starting_liquidity"`
+	Premiums          BigInt  `gorm:"column:premiums;not null" json:"premiums"`
+	TotalPayout       BigInt  `gorm:"column:total_payout;not null" json:"total_payout"`
+	RoundReturn       float64 `gorm:"column:round_return;not null" json:"round_return"`
+	AnnualizedYield   float64 `gorm:"column:annualized_yield;not null" json:"annualized_yield"`
+	PremiumRatio      float64 `gorm:"column:premium_ratio;not null" json:"premium_ratio"`
+	LossRatio         float64 `gorm:"column:loss_ratio;not null" json:"loss_ratio"`
+	RoundDuration     uint64  `gorm:"column:round_duration;not null" json:"round_duration"`
+	SettledBlock      uint64  `gorm:"column:settled_block;not null" json:"settled_block"`
+}
+
+// VaultMetrics aggregates the RoundMetrics of a vault's latest settled
+// rounds. It is computed on request and not stored.
+type VaultMetrics struct {
+	VaultAddress           string  `json:"vault_address"`
+	Rounds                 int     `json:"rounds"`
+	StartingLiquidity      BigInt  `json:"starting_liquidity"`
+	Premiums               BigInt  `json:"premiums"`
+	TotalPayout            BigInt  `json:"total_payout"`
+	AverageRoundReturn     float64 `json:"average_round_return"`
+	AverageAnnualizedYield float64 `json:"average_annualized_yield"`
+	CumulativeReturn       float64 `json:"cumulative_return"`
+	PremiumRatio           float64 `json:"premium_ratio"`
+	LossRatio              float64 `json:"loss_ratio"`
+}
+
+// L1Request is a Fossil request fulfilled for a vault, carrying the pricing
+// data of round RoundID
+type L1Request struct {
+	VaultAddress        string `gorm:"column:vault_address;primaryKey" json:"vault_address"`
+	RoundID             BigInt `gorm:"column:round_id;primaryKey" json:"round_id"`
+	RequestID           string `gorm:"column:request_id;not null" json:"request_id"`
+	Caller              string `gorm:"column:caller;not null" json:"caller"`
+	FossilClientAddress string `gorm:"column:fossil_client_address;not null" json:"fossil_client_address"`
+	StrikePrice         BigInt `gorm:"column:strike_price;not null" json:"strike_price"`
+	CapLevel            BigInt `gorm:"column:cap_level;not null" json:"cap_level"`
+	ReservePrice        BigInt `gorm:"column:reserve_price;not null" json:"reserve_price"`
+	FulfilledBlock      uint64 `gorm:"column:fulfilled_block;not null" json:"fulfilled_block"`
+	FulfilledAt         uint64 `gorm:"column:fulfilled_at;not null" json:"fulfilled_at"`
+}
+
+type IndexerCheckpoint struct {
+	ID          uint   `gorm:"column:id;primaryKey" json:"id"`
+	BlockNumber uint64 `gorm:"column:block_number;not null" json:"block_number"`
+	BlockHash   string `gorm:"column:block_hash;not null" json:"block_hash"`
+}
+
+type Event struct {
+	BlockNumber      uint64     `gorm:"column:block_number;not null" json:"block_number"`
+	BlockHash        string     `gorm:"column:block_hash;not null" json:"block_hash"`
+	Timestamp        uint64     `gorm:"column:timestamp;not null" json:"timestamp"`
+	TransactionHash  string     `gorm:"column:transaction_hash;not null" json:"transaction_hash"`
+	TransactionIndex uint64     `gorm:"column:transaction_index;not null" json:"transaction_index"`
+	EventIndex       uint64     `gorm:"column:event_index;not null" json:"event_index"`
+	FromAddress      string     `gorm:"column:from_address;not null" json:"from_address"`
+	EventName        string     `gorm:"column:event_name;not null" json:"event_name"`
+	Keys             StringList `gorm:"column:keys;type:jsonb;not null" json:"keys"`
+	Data             StringList `gorm:"column:data;type:jsonb;not null" json:"data"`
+}
+
+// QuarantinedEvent is an event that could not be decoded or applied, kept
+// with its coordinates so it can be inspected and replayed
+type QuarantinedEvent struct {
+	ID               uint64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	BlockNumber      uint64     `gorm:"column:block_number;not null" json:"block_number"`
+	BlockHash        string     `gorm:"column:block_hash;not null" json:"block_hash"`
+	Timestamp        uint64     `gorm:"column:timestamp;not null" json:"timestamp"`
+	TransactionHash  string     `gorm:"column:transaction_hash;not null" json:"transaction_hash"`
+	TransactionIndex uint64     `gorm:"column:transaction_index;not null" json:"transaction_index"`
+	EventIndex       uint64     `gorm:"column:event_index;not null" json:"event_index"`
+	FromAddress      string     `gorm:"column:from_address;not null" json:"from_address"`
+	EventName        string     `gorm:"column:event_name;not null" json:"event_name"`
+	Member           string     `gorm:"column:member;not null" json:"member"`
+	Keys             StringList `gorm:"column:keys;type:jsonb;not null" json:"keys"`
+	Data             StringList `gorm:"column:data;type:jsonb;not null" json:"data"`
+	Error            string     `gorm:"column:error;not null" json:"error"`
+	Handler          string     `gorm:"column:handler;not null" json:"handler"`
+	Retries          int        `gorm:"column:retries;not null" json:"retries"`
+}
+
+// Event returns the quarantined event in the form stored in the Events table
+func (q QuarantinedEvent) Event() Event {
+	return Event{
+		BlockNumber:      q.BlockNumber,
+		BlockHash:        q.BlockHash,
+		Timestamp:        q.Timestamp,
+		TransactionHash:  q.TransactionHash,
+		TransactionIndex: q.TransactionIndex,
+		EventIndex:       q.EventIndex,
+		FromAddress:      q.FromAddress,
+		EventName:        q.EventName,
+		Keys:             q.Keys,
+		Data:             q.Data,
+	}
+}
+
+func (Vault) TableName() string {
+	return "Vault_Historic"
+}
+
+func (LiquidityProvider) TableName() string {
+	return "Liquidity_Providers_Historic"
 }
 
 func (VaultState) TableName() string {
@@ -165,3 +380,31 @@ func (Bid) TableName() string {
 func (OptionBuyer) TableName() string {
 	return "Option_Buyers"
 }
+
+func (IndexerCheckpoint) TableName() string {
+	return "Indexer_Checkpoint"
+}
+
+func (Event) TableName() string {
+	return "Events"
+}
+
+func (OptionHolder) TableName() string {
+	return "Option_Holders"
+}
+
+func (LPAction) TableName() string {
+	return "LP_Actions"
+}
+
+func (RoundMetrics) TableName() string {
+	return "Round_Metrics"
+}
+
+func (L1Request) TableName() string {
+	return "L1_Requests"
+}
+
+func (QuarantinedEvent) TableName() string {
+	return "Quarantined_Events"
+}
@@ -0,0 +1,294 @@
+// Package notify consumes the pg_notify channels fed by the triggers in
+// db/migrations and fans the decoded rows out to in-process subscribers.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"junoplugin/models"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Kind identifies the table an update comes from
+type Kind string
+
+const (
+	KindVault       Kind = "vault"
+	KindLP          Kind = "lp"
+	KindOptionRound Kind = "round"
+	KindOptionBuyer Kind = "buyer"
+	KindBid         Kind = "bid"
+)
+
+// channels maps each notify channel to the kind of row it carries
+var channels = map[string]Kind{
+	"vault_update": KindVault,
+	"lp_update":    KindLP,
+	"or_update":    KindOptionRound,
+	"ob_update":    KindOptionBuyer,
+	"bids_update":  KindBid,
+}
+
+// Update is one row change. Data holds a pointer to the matching model:
+// *models.VaultState, *models.LiquidityProviderState, *models.OptionRound,
+// *models.OptionBuyer or *models.Bid.
+type Update struct {
+	Kind      Kind        `json:"kind"`
+	Operation string      `json:"operation"`
+	Data      interface{} `json:"data"`
+	// vault is resolved through the round for buyer and bid rows, which
+	// carry no vault address of their own
+	vault string
+}
+
+// Addresses returns the vault, round and account (LP or buyer) the update
+// concerns; an empty string means the update carries no such address.
+func (u Update) Addresses() (vault, round, account string) {
+	switch data := u.Data.(type) {
+	case *models.VaultState:
+		return data.Address, data.CurrentRoundAddress, ""
+	case *models.LiquidityProviderState:
+		return data.VaultAddress, "", data.Address
+	case *models.OptionRound:
+		return data.VaultAddress, data.Address, ""
+	case *models.OptionBuyer:
+		return u.vault, data.RoundAddress, data.Address
+	case *models.Bid:
+		return u.vault, data.RoundAddress, data.BuyerAddress
+	}
+	return "", "", ""
+}
+
+// Filter selects updates for a subscription. Empty fields match everything.
+type Filter struct {
+	Kinds   []Kind `json:"kinds"`
+	Vault   string `json:"vault"`
+	Round   string `json:"round"`
+	Account string `json:"account"`
+}
+
+func (f Filter) Matches(u Update) bool {
+	if len(f.Kinds) > 0 {
+		found := false
+		for _, kind := range f.Kinds {
+			if kind == u.Kind {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	vault, round, account := u.Addresses()
+	return (f.Vault == "" || f.Vault == vault) &&
+		(f.Round == "" || f.Round == round) &&
+		(f.Account == "" || f.Account == account)
+}
+
+func decode(channel string, payload string) (Update, error) {
+	kind, ok := channels[channel]
+	if !ok {
+		return Update{}, fmt.Errorf("unknown channel %s", channel)
+	}
+	var data interface{}
+	switch kind {
+	case KindVault:
+		data = &models.VaultState{}
+	case KindLP:
+		data = &models.LiquidityProviderState{}
+	case KindOptionRound:
+		data = &models.OptionRound{}
+	case KindOptionBuyer:
+		data = &models.OptionBuyer{}
+	case KindBid:
+		data = &models.Bid{}
+	}
+	var envelope struct {
+		Operation string          `json:"operation"`
+		Payload   json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(payload), &envelope); err != nil {
+		return Update{}, fmt.Errorf("decoding %s notification: %w", channel, err)
+	}
+	if err := json.Unmarshal(envelope.Payload, data); err != nil {
+		return Update{}, fmt.Errorf("decoding %s row: %w", channel, err)
+	}
+	return Update{Kind: kind, Operation: envelope.Operation, Data: data}, nil
+}
+
+// Subscription receives the updates matching its filter on C. Updates are
+// dropped for a subscriber that falls more than its buffer behind, so a slow
+// client can't stall the others.
+type Subscription struct {
+	C        <-chan Update
+	updates  chan Update
+	filter   Filter
+	listener *Listener
+}
+
+func (s *Subscription) Close() {
+	s.listener.unsubscribe(s)
+}
+
+// Listener holds a dedicated connection LISTENing on every notify channel
+type Listener struct {
+	dsn    string
+	log    *log.Logger
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+	// roundVaults caches the vault of every round seen, only touched by
+	// the listen goroutine
+	roundVaults map[string]string
+}
+
+func New(dsn string, logger *log.Logger) *Listener {
+	return &Listener{
+		dsn:         dsn,
+		log:         logger,
+		subs:        make(map[*Subscription]struct{}),
+		roundVaults: make(map[string]string),
+	}
+}
+
+func (l *Listener) Subscribe(filter Filter, buffer int) *Subscription {
+	updates := make(chan Update, buffer)
+	sub := &Subscription{C: updates, updates: updates, filter: filter, listener: l}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		close(updates)
+		return sub
+	}
+	l.subs[sub] = struct{}{}
+	return sub
+}
+
+func (l *Listener) unsubscribe(sub *Subscription) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.subs[sub]; ok {
+		delete(l.subs, sub)
+		close(sub.updates)
+	}
+}
+
+func (l *Listener) publish(update Update) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for sub := range l.subs {
+		if !sub.filter.Matches(update) {
+			continue
+		}
+		select {
+		case sub.updates <- update:
+		default:
+		}
+	}
+}
+
+// Run listens until ctx is cancelled, reconnecting with backoff whenever the
+// connection drops. Notifications sent while disconnected are lost.
+func (l *Listener) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		connectedAt := time.Now()
+		err := l.listen(ctx)
+		lasted := time.Since(connectedAt)
+		if ctx.Err() != nil {
+			l.closeAll()
+			return
+		}
+		l.log.Printf("Notify listener disconnected, retrying in %s: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			l.closeAll()
+			return
+		case <-time.After(backoff):
+		}
+		if lasted > time.Minute {
+			backoff = time.Second
+		} else if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+func (l *Listener) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	for channel := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		update, err := decode(notification.Channel, notification.Payload)
+		if err != nil {
+			l.log.Printf("Notify listener: %v", err)
+			continue
+		}
+		if err := l.resolveVault(ctx, conn, &update); err != nil {
+			return err
+		}
+		l.publish(update)
+	}
+}
+
+// resolveVault fills in the vault of buyer and bid updates from their round,
+// so vault filters match them like the other kinds. Rounds are looked up once
+// and cached; or_update notifications keep the cache warm.
+func (l *Listener) resolveVault(ctx context.Context, conn *pgx.Conn, update *Update) error {
+	var roundAddress string
+	switch data := update.Data.(type) {
+	case *models.OptionRound:
+		l.roundVaults[data.Address] = data.VaultAddress
+		return nil
+	case *models.OptionBuyer:
+		roundAddress = data.RoundAddress
+	case *models.Bid:
+		roundAddress = data.RoundAddress
+	default:
+		return nil
+	}
+	if vault, ok := l.roundVaults[roundAddress]; ok {
+		update.vault = vault
+		return nil
+	}
+	var vault string
+	err := conn.QueryRow(ctx, `SELECT COALESCE(vault_address, '') FROM "Option_Rounds" WHERE address = $1`, roundAddress).Scan(&vault)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The round was reverted before the notification was read
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("resolving the vault of round %s: %w", roundAddress, err)
+	}
+	l.roundVaults[roundAddress] = vault
+	update.vault = vault
+	return nil
+}
+
+func (l *Listener) closeAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	for sub := range l.subs {
+		delete(l.subs, sub)
+		close(sub.updates)
+	}
+}
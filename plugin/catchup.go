@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NethermindEth/juno/core"
+	junoplugin "github.com/NethermindEth/juno/plugin"
+)
+
+// blockSource is the subset of Juno's starknetdata.StarknetData used to
+// replay blocks that were produced while the plugin was not attached.
+type blockSource interface {
+	BlockByNumber(ctx context.Context, blockNumber uint64) (*core.Block, error)
+}
+
+// queuedRevert is a RevertBlock call received during a catch-up
+type queuedRevert struct {
+	from, to *core.Block
+}
+
+// nextBlock returns the first block that still has to be indexed: the one
+// after the checkpoint, or the cursor when nothing was indexed yet. A zero
+// cursor without a checkpoint starts at the first block Juno hands over.
+func (p *pitchlakePlugin) nextBlock(head uint64) uint64 {
+	switch {
+	case p.checkpoint != nil:
+		return p.checkpoint.BlockNumber + 1
+	case p.cursor > 0:
+		return p.cursor
+	}
+	return head
+}
+
+// startCatchUp replays the blocks from the checkpoint up to head, the block
+// Juno just stored, from the feeder in the background. Juno keeps syncing
+// meanwhile: later blocks only move the head the replay follows and reverts
+// are queued, so the replay never indexes a block Juno has not stored and
+// live indexing resumes once it reaches Juno's head. Callers hold p.mu.
+func (p *pitchlakePlugin) startCatchUp(from, head uint64) error {
+	if p.blockSource == nil {
+		return fmt.Errorf(
+			"blocks %d-%d were synced by Juno but not indexed and no feeder URL is configured to catch up on them",
+			from,
+			head-1,
+		)
+	}
+	p.log.Printf("Catching up on blocks %d-%d in the background", from, head)
+	p.catchingUp = true
+	p.junoHead = head
+	p.catchUpDone.Add(1)
+	go func() {
+		defer p.catchUpDone.Done()
+		p.catchUp()
+	}()
+	return nil
+}
+
+// catchUp indexes one block at a time through the same path as NewBlock
+// until it reaches Juno's head. Failed fetches or blocks are retried with
+// backoff until Shutdown.
+func (p *pitchlakePlugin) catchUp() {
+	backoff := time.Second
+	for {
+		p.mu.Lock()
+		p.applyQueuedReverts()
+		blockNumber := p.nextBlock(p.junoHead)
+		if blockNumber > p.junoHead {
+			p.catchingUp = false
+			p.log.Printf("Caught up to block %d", p.junoHead)
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		err := p.catchUpBlock(blockNumber)
+		if err == nil {
+			backoff = time.Second
+			continue
+		}
+		p.log.Printf("Catch-up of block %d failed, retrying in %s: %v", blockNumber, backoff, err)
+		select {
+		case <-p.catchUpCtx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+func (p *pitchlakePlugin) catchUpBlock(blockNumber uint64) error {
+	block, err := p.blockSource.BlockByNumber(p.catchUpCtx, blockNumber)
+	if err != nil {
+		return fmt.Errorf("fetching block %d for catch-up: %w", blockNumber, err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// A revert may have moved Juno's head below the block while it was fetched
+	if blockNumber > p.junoHead {
+		return nil
+	}
+	return p.indexBlock(block)
+}
+
+// queueRevert records a RevertBlock received during a catch-up; Juno's head
+// moves back to the parent of the reverted block. Callers hold p.mu.
+func (p *pitchlakePlugin) queueRevert(from, to *junoplugin.BlockAndStateUpdate) {
+	revert := queuedRevert{from: from.Block}
+	if to != nil {
+		revert.to = to.Block
+	}
+	p.reverts = append(p.reverts, revert)
+	if from.Block.Number > 0 {
+		p.junoHead = from.Block.Number - 1
+	}
+}
+
+// applyQueuedReverts undoes the queued reverts of blocks the catch-up had
+// already indexed, in the order Juno reverted them. Callers hold p.mu.
+func (p *pitchlakePlugin) applyQueuedReverts() {
+	for _, revert := range p.reverts {
+		if err := p.revertBlock(revert.from, revert.to); err != nil {
+			p.log.Printf("Applying queued revert of block %d: %v", revert.from.Number, err)
+		}
+	}
+	p.reverts = nil
+}
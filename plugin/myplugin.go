@@ -1,32 +1,54 @@
 package main
 
 import (
-	"junoplugin/adaptors"
+	"context"
+	"errors"
+	"fmt"
+	"junoplugin/api"
+	"junoplugin/config"
 	"junoplugin/db"
+	"junoplugin/gql"
+	"junoplugin/indexer"
 	"junoplugin/models"
+	"junoplugin/notify"
+	"junoplugin/rpc"
 	"log"
-	"math/big"
-	"os"
-	"strconv"
+	"sync"
+	"time"
 
+	"github.com/NethermindEth/juno/clients/feeder"
 	"github.com/NethermindEth/juno/core"
 	"github.com/NethermindEth/juno/core/felt"
 	junoplugin "github.com/NethermindEth/juno/plugin"
+	adaptfeeder "github.com/NethermindEth/juno/starknetdata/feeder"
 )
 
-// Todo: push this stuff to a config file / cmd line
-
 //go:generate go build -buildmode=plugin -o ../../build/plugin.so ./example.go
 type pitchlakePlugin struct {
-	vaultHash         string
-	vaultAddressesMap map[string]struct{}
-	roundAddressesMap map[string]struct{}
-	deployer          string
-	udcAddress        string
-	db                *db.DB
-	log               *log.Logger
-	junoAdaptor       *adaptors.JunoAdaptor
-	cursor            uint64
+	db          *db.DB
+	log         *log.Logger
+	indexer     *indexer.Indexer
+	blockSource blockSource
+	cursor      uint64
+	checkpoint  *models.IndexerCheckpoint
+	// mu serializes Juno's callbacks with the background catch-up
+	mu sync.Mutex
+	// catchingUp is set while the blocks up to junoHead are replayed from
+	// the feeder; reverts received meanwhile wait in reverts
+	catchingUp  bool
+	junoHead    uint64
+	reverts     []queuedRevert
+	catchUpCtx  context.Context
+	stopCatchUp context.CancelFunc
+	catchUpDone sync.WaitGroup
+	// journalRetention is how many blocks back the change journal can revert
+	journalRetention uint64
+	errorPolicy      config.ErrorPolicy
+	halted           *haltedOp
+	api              *api.Server
+	// readDB is the API surfaces' own pool, see db.Open
+	readDB     *db.DB
+	stopNotify context.CancelFunc
 }
 
 // Important: "JunoPluginInstance" needs to be exported for Juno to load the plugin correctly
@@ -36,52 +58,91 @@ var JunoPluginInstance = pitchlakePlugin{}
 var _ junoplugin.JunoPlugin = (*pitchlakePlugin)(nil)
 
 func (p *pitchlakePlugin) Init() error {
-	dbUrl := os.Getenv("DB_URL")
-	udcAddress := os.Getenv("UDC_ADDRESS")
-	p.udcAddress = udcAddress
-	p.vaultAddressesMap = make(map[string]struct{})
-	p.roundAddressesMap = make(map[string]struct{})
-	dbClient, err := db.Init(dbUrl)
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	p.errorPolicy = cfg.ErrorPolicy
+	dbClient, err := db.Init(cfg.DBURL)
 	if err != nil {
 		return err
 	}
 	p.db = dbClient
-	vaultAddresses, err := p.db.GetVaultAddresses()
+	p.indexer, err = indexer.New(p.db, cfg, true)
 	if err != nil {
 		return err
 	}
+	if err := p.indexer.LoadAddressMaps(); err != nil {
+		return err
+	}
+
+	p.cursor = cfg.Cursor
+	p.journalRetention = cfg.JournalRetention
+	p.log = log.Default()
 
-	//Map
-	for _, vaultAddress := range vaultAddresses {
-		p.vaultAddressesMap[vaultAddress] = struct{}{}
-		roundAddresses, err := p.db.GetRoundAddressess(vaultAddress)
+	p.checkpoint, err = p.db.GetCheckpoint()
+	if err != nil {
+		return err
+	}
+	if p.checkpoint != nil {
+		p.log.Printf("Resuming from checkpoint block %d (%s)", p.checkpoint.BlockNumber, p.checkpoint.BlockHash)
+	}
+
+	if cfg.API.Enabled {
+		p.readDB, err = db.Open(cfg.DBURL)
 		if err != nil {
 			return err
 		}
-		//Round Address Map
-		for _, roundAddress := range *roundAddresses {
-			p.roundAddressesMap[roundAddress] = struct{}{}
+		p.api = api.New(p.readDB, cfg.API.Port, p.log)
+		listener := notify.New(cfg.DBURL, p.log)
+		var notifyCtx context.Context
+		notifyCtx, p.stopNotify = context.WithCancel(context.Background())
+		go listener.Run(notifyCtx)
+		p.api.ServeUpdates(listener, cfg.API.AllowedOrigins)
+		graphqlHandler, err := gql.NewHandler(p.readDB, listener, cfg.API.AllowedOrigins, p.log)
+		if err != nil {
+			return err
 		}
-	}
-
-	p.junoAdaptor = &adaptors.JunoAdaptor{}
-	p.vaultHash = os.Getenv("VAULT_HASH")
-	p.deployer = os.Getenv("DEPLOYER")
-	cursor := os.Getenv("CURSOR")
-	if cursor != "" {
-		p.cursor, err = strconv.ParseUint(cursor, 10, 64)
+		p.api.Handle("/graphql", graphqlHandler)
+		rpcHTTP, rpcWebSocket, err := rpc.New(p.readDB).HTTPHandlers(p.log)
 		if err != nil {
 			return err
 		}
+		p.api.Handle("/rpc", rpcHTTP)
+		p.api.Handle("/rpc/ws", rpcWebSocket)
+		if err := p.api.Start(); err != nil {
+			return err
+		}
 	}
-	p.log = log.Default()
 
-	//Add function to catch up on vaults/rounds that are not synced to currentBlock
+	p.catchUpCtx, p.stopCatchUp = context.WithCancel(context.Background())
+	if cfg.FeederURL == "" {
+		p.log.Printf("No feeder URL configured, indexing stops at the first block Juno synced without the plugin")
+	} else {
+		p.blockSource = adaptfeeder.New(feeder.NewClient(cfg.FeederURL))
+	}
 	return nil
 }
 
 func (p *pitchlakePlugin) Shutdown() error {
 	p.log.Println("Calling Shutdown() in plugin")
+	if p.stopCatchUp != nil {
+		p.stopCatchUp()
+	}
+	p.catchUpDone.Wait()
+	if p.stopNotify != nil {
+		p.stopNotify()
+	}
+	if p.api != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := p.api.Shutdown(ctx); err != nil {
+			p.log.Printf("Stopping API server: %v", err)
+		}
+	}
+	if p.readDB != nil {
+		p.readDB.Close()
+	}
 	p.db.Close()
 	return nil
 }
@@ -92,370 +153,197 @@ func (p *pitchlakePlugin) NewBlock(
 	newClasses map[felt.Felt]core.Class,
 ) error {
 
-	p.db.Begin()
 	p.log.Println("ExamplePlugin NewBlock called")
-	if block.Number < p.cursor {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.catchingUp {
+		p.junoHead = block.Number
+		return nil
+	}
+	if p.checkpoint == nil && block.Number < p.cursor {
 		log.Printf("Pre-cursor block")
 		return nil
 	}
-
-	var err error
-	for _, receipt := range block.Receipts {
-		for i, event := range receipt.Events {
-			fromAddress := event.From.String()
-
-			if fromAddress == p.udcAddress {
-				err = p.processUDC(receipt.Events, event, i, block.Number, block.Timestamp)
-			} else {
-
-				//HashMap processing
-				if _, exists := p.vaultAddressesMap[fromAddress]; exists {
-					err = p.processVaultEvent(fromAddress, event, block.Number, block.Timestamp)
-				} else if _, exists := p.roundAddressesMap[fromAddress]; exists {
-					err = p.processRoundEvent(fromAddress, event, block.Number)
-				}
-				if err != nil {
-					log.Fatal(err)
-				}
-			}
-		}
+	if err := p.resume(); err != nil {
+		return err
 	}
-	p.db.Commit()
-	return nil
+	if from := p.nextBlock(block.Number); block.Number > from {
+		return p.startCatchUp(from, block.Number)
+	}
+	return p.indexBlock(block)
 }
 
-func (p *pitchlakePlugin) RevertBlock(
-	from,
-	to *junoplugin.BlockAndStateUpdate,
-	reverseStateDiff *core.StateDiff,
-) error {
-	p.db.Begin()
-	length := len(from.Block.Receipts)
-	var err error
-	for i := length - 1; i >= 0; i-- {
-		receipt := from.Block.Receipts[i]
-		for _, event := range receipt.Events {
-
-			fromAddress := event.From.String()
+// indexBlock processes every event of the block and advances the checkpoint.
+// It is shared by NewBlock and the catch-up replay. Callers hold p.mu.
+func (p *pitchlakePlugin) indexBlock(block *core.Block) error {
+	if err := p.resume(); err != nil {
+		return err
+	}
+	return p.applyBlock(block)
+}
 
-			//HashMap
-			if _, exists := p.vaultAddressesMap[fromAddress]; exists {
-				err = p.revertVaultEvent(fromAddress, event, from.Block.Number)
-			} else if _, exists := p.roundAddressesMap[fromAddress]; exists {
-				err = p.revertRoundEvent(fromAddress, event, from.Block.Number)
-			}
-			if err != nil {
-				log.Fatal(err)
-			}
-		}
+// applyBlock indexes the block unless the checkpoint shows it already was
+func (p *pitchlakePlugin) applyBlock(block *core.Block) error {
+	indexed, err := p.checkBlockAgainstCheckpoint(block)
+	if err != nil {
+		return err
+	}
+	if indexed {
+		log.Printf("Block %d already indexed", block.Number)
+		return nil
+	}
+	err = p.runWithPolicy(fmt.Sprintf("indexing block %d", block.Number), func() error {
+		return p.indexBlockTx(block)
+	})
+	if err != nil {
+		return p.haltOn(err, block, func() error { return p.applyBlock(block) })
 	}
-	p.db.Commit()
 	return nil
 }
 
-func (p *pitchlakePlugin) processUDC(
-	events []*core.Event,
-	event *core.Event,
-	index int,
-	blockNumber uint64,
-	timestamp uint64,
-) error {
-
-	eventHash := adaptors.Keccak256("ContractDeployed")
-	if eventHash == event.Keys[0].String() {
-		address := adaptors.FeltToHexString(event.Data[0].Bytes())
-		deployer := adaptors.FeltToHexString(event.Data[1].Bytes())
-		classHash := adaptors.FeltToHexString(event.Data[3].Bytes())
-		//ClassHash and deployer filter, may use other filters here
-
-		if classHash == p.vaultHash && deployer == p.deployer {
-			fossilClientAddress, ethAddress, optionRoundClassHash, alpha, strikeLevel, roundTransitionDuration, auctionDuration, roundDuration := p.junoAdaptor.ContractDeployed(*event)
-			p.vaultAddressesMap[address] = struct{}{}
-			vault := models.VaultState{
-				CurrentRound:          *models.NewBigInt("1"),
-				UnlockedBalance:       *models.NewBigInt("0"),
-				LockedBalance:         *models.NewBigInt("0"),
-				StashedBalance:        *models.NewBigInt("0"),
-				Address:               address,
-				LatestBlock:           blockNumber,
-				FossilClientAddress:   fossilClientAddress,
-				EthAddress:            ethAddress,
-				OptionRoundClassHash:  optionRoundClassHash,
-				Alpha:                 alpha,
-				StrikeLevel:           strikeLevel,
-				RoundTransitionPeriod: roundTransitionDuration,
-				AuctionDuration:       auctionDuration,
-				RoundDuration:         roundDuration,
-				DeploymentDate:        timestamp,
-			}
-			if err := p.db.CreateVault(&vault); err != nil {
-				return err
+func (p *pitchlakePlugin) indexBlockTx(block *core.Block) error {
+	blockHash := block.Hash.String()
+	err := p.db.WithBlockTx(func(tx *db.DB) error {
+		if err := tx.SetJournalBlock(block.Number); err != nil {
+			return err
+		}
+		for txIndex, receipt := range block.Receipts {
+			for i := range receipt.Events {
+				if err := p.processEventWithPolicy(block, txIndex, i); err != nil {
+					return err
+				}
 			}
-			log.Printf("index %v", index)
-			if err := p.processVaultEvent(address, events[index-1], blockNumber, timestamp); err != nil {
+		}
+		if block.Number > p.journalRetention {
+			if err := tx.PruneJournal(block.Number - p.journalRetention); err != nil {
 				return err
 			}
 		}
-
+		return tx.UpsertCheckpoint(block.Number, blockHash)
+	})
+	if err != nil {
+		return p.resetAfterRollback(err)
 	}
+	p.checkpoint = &models.IndexerCheckpoint{BlockNumber: block.Number, BlockHash: blockHash}
 	return nil
 }
 
-func (p *pitchlakePlugin) processVaultEvent(
-	vaultAddress string,
-	event *core.Event,
-	blockNumber uint64,
-	timestamp uint64,
-) error {
-
-	eventName, err := adaptors.DecodeEventNameVault(event.Keys[0].String())
-	if err != nil {
-		log.Printf("Unknown Event")
-		return nil
+// resetAfterRollback drops the address map entries added by a rolled back transaction
+func (p *pitchlakePlugin) resetAfterRollback(err error) error {
+	if loadErr := p.indexer.LoadAddressMaps(); loadErr != nil {
+		return errors.Join(err, loadErr)
 	}
-	switch eventName {
-	case "Deposit": //Add withdrawQueue and collect queue case based on event
-		lpAddress,
-			lpUnlocked,
-			vaultUnlocked := p.junoAdaptor.DepositOrWithdraw(*event)
-
-		err = p.db.DepositIndex(vaultAddress, lpAddress, lpUnlocked, vaultUnlocked, blockNumber)
-		//Map the other parameters as well
-	case "Withdrawal":
-		lpAddress,
-			lpUnlocked,
-			vaultUnlocked := p.junoAdaptor.DepositOrWithdraw(*event)
-
-		err = p.db.WithdrawIndex(vaultAddress, lpAddress, lpUnlocked, vaultUnlocked, blockNumber)
-	case "WithdrawalQueued":
-		lpAddress,
-			bps,
-			roundId,
-			accountQueuedBefore,
-			accountQueuedNow,
-			vaultQueuedNow := p.junoAdaptor.WithdrawalQueued(*event)
-
-		err = p.db.WithdrawalQueuedIndex(
-			lpAddress,
-			vaultAddress,
-			roundId,
-			bps,
-			accountQueuedBefore,
-			accountQueuedNow,
-			vaultQueuedNow,
-		)
+	return err
+}
 
-	case "StashWithdrawn":
-		lpAddress, amount, vaultStashed := p.junoAdaptor.StashWithdrawn(*event)
-		err = p.db.StashWithdrawnIndex(
-			vaultAddress,
-			lpAddress,
-			amount,
-			vaultStashed,
-			blockNumber,
+// checkBlockAgainstCheckpoint reports whether the block was already indexed and
+// errors on gaps or hash mismatches between the checkpoint and Juno's chain
+func (p *pitchlakePlugin) checkBlockAgainstCheckpoint(block *core.Block) (bool, error) {
+	if p.checkpoint == nil {
+		return false, nil
+	}
+	switch {
+	case block.Number < p.checkpoint.BlockNumber:
+		return true, nil
+	case block.Number == p.checkpoint.BlockNumber:
+		if block.Hash.String() != p.checkpoint.BlockHash {
+			return false, fmt.Errorf(
+				"block %d hash %s does not match checkpoint hash %s",
+				block.Number,
+				block.Hash.String(),
+				p.checkpoint.BlockHash,
+			)
+		}
+		return true, nil
+	case block.Number > p.checkpoint.BlockNumber+1:
+		return false, fmt.Errorf(
+			"gap between checkpoint block %d and block %d",
+			p.checkpoint.BlockNumber,
+			block.Number,
 		)
-	case "OptionRoundDeployed":
-
-		optionRound := p.junoAdaptor.RoundDeployed(*event)
-		optionRound.DeploymentDate = timestamp
-		err = p.db.RoundDeployedIndex(optionRound)
-		p.roundAddressesMap[optionRound.Address] = struct{}{}
 	}
-	if err != nil {
-		return err
+	if block.ParentHash.String() != p.checkpoint.BlockHash {
+		return false, fmt.Errorf(
+			"block %d parent hash %s does not match checkpoint hash %s",
+			block.Number,
+			block.ParentHash.String(),
+			p.checkpoint.BlockHash,
+		)
 	}
-	return nil
+	return false, nil
 }
 
-func (p *pitchlakePlugin) processRoundEvent(
-	roundAddress string,
-	event *core.Event,
-	blockNumber uint64,
+func (p *pitchlakePlugin) RevertBlock(
+	from,
+	to *junoplugin.BlockAndStateUpdate,
+	reverseStateDiff *core.StateDiff,
 ) error {
-	var err error
-	prevStateOptionRound, err := p.db.GetOptionRoundByAddress(roundAddress)
-	if err != nil {
-		return err
-	}
-
-	eventName, err := adaptors.DecodeEventNameRound(event.Keys[0].String())
-	if err != nil {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.catchingUp {
+		p.queueRevert(from, to)
 		return nil
 	}
-	switch eventName {
-	case "PricingDataSet":
-		strikePrice, capLevel, reservePrice := p.junoAdaptor.PricingDataSet(*event)
-		err = p.db.PricingDataSetIndex(roundAddress, strikePrice, capLevel, reservePrice)
-	case "AuctionStarted":
-		availableOptions, startingLiquidity := p.junoAdaptor.AuctionStarted(*event)
-		err = p.db.AuctionStartedIndex(
-			prevStateOptionRound.VaultAddress,
-			roundAddress,
-			blockNumber,
-			availableOptions,
-			startingLiquidity,
-		)
-
-	case "AuctionEnded":
-		optionsSold,
-			clearingPrice,
-			unsoldLiquidity,
-			clearingNonce,
-			premiums := p.junoAdaptor.AuctionEnded(*event)
-
-		err = p.db.AuctionEndedIndex(
-			*prevStateOptionRound,
-			roundAddress,
-			blockNumber,
-			clearingNonce,
-			optionsSold,
-			clearingPrice,
-			premiums,
-			unsoldLiquidity,
-		)
-	case "OptionRoundSettled":
-		settlementPrice, payoutPerOption := p.junoAdaptor.RoundSettled(*event)
-		if err := p.db.RoundSettledIndex(
-			*prevStateOptionRound,
-			roundAddress,
-			blockNumber,
-			settlementPrice,
-			prevStateOptionRound.SoldOptions,
-			payoutPerOption,
-		); err != nil {
-			return err
-		}
-	case "BidPlaced":
-		bid, buyer := p.junoAdaptor.BidPlaced(*event)
-		err = p.db.BidPlacedIndex(bid, buyer)
-	case "BidUpdated":
-		bidId, price, _, treeNonceNew := p.junoAdaptor.BidUpdated(*event)
-		err = p.db.BidUpdatedIndex(event.From.String(), bidId, price, treeNonceNew)
-	case "OptionsMinted", "OptionsExercised":
-		buyerAddress := adaptors.FeltToHexString(event.Keys[1].Bytes())
-
-		err = p.db.UpdateOptionBuyerFields(
-			buyerAddress,
-			roundAddress,
-			map[string]interface{}{
-				"has_minted": true,
-			})
-	case "UnusedBidsRefunded":
-		buyerAddress := adaptors.FeltToHexString(event.Keys[1].Bytes())
-		err = p.db.UpdateOptionBuyerFields(
-			buyerAddress,
-			roundAddress,
-			map[string]interface{}{
-				"has_refunded": true,
-			})
-	case "Transfer":
-	}
-
-	if err != nil {
-		return err
+	var toBlock *core.Block
+	if to != nil {
+		toBlock = to.Block
 	}
-	return nil
+	return p.revertBlock(from.Block, toBlock)
 }
 
-func (p *pitchlakePlugin) revertVaultEvent(vaultAddress string, event *core.Event, blockNumber uint64) error {
-	eventName, err := adaptors.DecodeEventNameVault(event.Keys[0].String())
-	if err != nil {
-		return err
-	}
-	switch eventName {
-	case "Deposit", "Withdraw",
-		"StashWithdrawn": //Add withdraw queue
-
-		lpAddress := adaptors.FeltToHexString(event.Keys[1].Bytes())
-		err = p.db.DepositOrWithdrawRevert(vaultAddress, lpAddress, blockNumber)
-	case "WithdrawalQueued":
-		lpAddress,
-			bps,
-			roundId,
-			accountQueuedBefore,
-			accountQueuedNow,
-			vaultQueuedNow := p.junoAdaptor.WithdrawalQueued(*event)
-
-		err = p.db.WithdrawalQueuedRevertIndex(
-			lpAddress,
-			vaultAddress,
-			roundId,
-			bps,
-			accountQueuedBefore,
-			accountQueuedNow,
-			vaultQueuedNow,
-			blockNumber,
-		)
-	case "OptionRoundDeployed":
-		roundAddress := adaptors.FeltToHexString(event.Data[2].Bytes())
-		err = p.db.DeleteOptionRound(roundAddress)
+// revertBlock undoes from, the checkpoint block, leaving to (nil for the
+// genesis block) as the new checkpoint. Callers hold p.mu.
+func (p *pitchlakePlugin) revertBlock(from, to *core.Block) error {
+	if p.halted != nil && p.halted.block != nil && p.halted.block.Number >= from.Number {
+		// Juno dropped the block indexing halted on, there is nothing to retry
+		p.log.Printf("Block %d reverted, no longer retrying: %v", p.halted.block.Number, p.halted.err)
+		p.halted = nil
 	}
-	if err != nil {
+	if err := p.resume(); err != nil {
 		return err
 	}
-
-	return nil
+	return p.applyRevert(from, to)
 }
 
-func (p *pitchlakePlugin) revertRoundEvent(roundAddress string, event *core.Event, blockNumber uint64) error {
-	eventName, err := adaptors.DecodeEventNameRound(event.Keys[0].String())
-	if err != nil {
-		return err
+// applyRevert reverts from unless the checkpoint shows it was not indexed
+func (p *pitchlakePlugin) applyRevert(from, to *core.Block) error {
+	if p.checkpoint == nil || p.checkpoint.BlockNumber != from.Number {
+		log.Printf("Block %d not indexed, nothing to revert", from.Number)
+		return nil
 	}
-	prevStateOptionRound, err := p.db.GetOptionRoundByAddress(roundAddress)
+	err := p.runWithPolicy(fmt.Sprintf("reverting block %d", from.Number), func() error {
+		return p.revertBlockTx(from, to)
+	})
 	if err != nil {
-		return err
+		return p.haltOn(err, nil, func() error { return p.applyRevert(from, to) })
 	}
-	switch eventName {
-	case "AuctionStarted":
-		err = p.db.AuctionStartedRevert(prevStateOptionRound.VaultAddress, roundAddress, blockNumber)
-	case "AuctionEnded":
-		err = p.db.AuctionEndedRevert(prevStateOptionRound.VaultAddress, roundAddress, blockNumber)
-
-	case "OptionRoundSettled":
-		err = p.db.RoundSettledRevert(prevStateOptionRound.VaultAddress, roundAddress, blockNumber)
-	case "BidAccepted":
-		id := event.Data[1].String()
-		err = p.db.BidAcceptedRevert(id, roundAddress)
-	case "BidUpdated":
-		bidId, amount, treeNonceOld, _ := p.junoAdaptor.BidUpdated(*event)
-		p.db.BidUpdatedRevert(bidId, amount, treeNonceOld)
-	case "OptionsMinted":
-		buyerAddress := adaptors.FeltToHexString(event.Keys[1].Bytes())
-		err = p.db.UpdateOptionBuyerFields(
-			buyerAddress,
-			roundAddress,
-			map[string]interface{}{
-				"has_minted": false,
-			})
-	case "OptionsExercised":
-		buyerAddress := adaptors.FeltToHexString(event.Keys[1].Bytes())
-		mintableOptionsExercised := adaptors.CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
+	return nil
+}
 
-		zero := models.BigInt{
-			Int: big.NewInt(0),
+func (p *pitchlakePlugin) revertBlockTx(from, to *core.Block) error {
+	err := p.db.WithBlockTx(func(tx *db.DB) error {
+		if err := tx.RevertBlock(from.Number); err != nil {
+			return err
 		}
-		if mintableOptionsExercised.Cmp(zero.Int) == 1 {
-			err = p.db.UpdateOptionBuyerFields(
-				buyerAddress,
-				roundAddress,
-				map[string]interface{}{
-					"has_minted": false,
-				})
+		if err := tx.DeleteEventsForBlock(from.Number); err != nil {
+			return err
 		}
-	case "UnusedBidsRefunded":
-		buyerAddress := adaptors.FeltToHexString(event.Keys[1].Bytes())
-		err = p.db.UpdateOptionBuyerFields(
-			buyerAddress,
-			roundAddress,
-			map[string]interface{}{
-				"has_refunded": false,
-			})
-
-	case "Transfer":
-	}
+		if err := tx.DeleteQuarantinedEventsForBlock(from.Number); err != nil {
+			return err
+		}
+		if to == nil {
+			return tx.DeleteCheckpoint()
+		}
+		return tx.UpsertCheckpoint(to.Number, to.Hash.String())
+	})
 	if err != nil {
-		log.Fatal(err)
+		return p.resetAfterRollback(err)
 	}
-	return nil
+	if to == nil {
+		p.checkpoint = nil
+	} else {
+		p.checkpoint = &models.IndexerCheckpoint{BlockNumber: to.Number, BlockHash: to.Hash.String()}
+	}
+	// Vaults and rounds deployed in the reverted block no longer exist
+	return p.indexer.LoadAddressMaps()
 }
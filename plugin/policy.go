@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"junoplugin/adaptors"
+	"junoplugin/config"
+	"time"
+
+	"github.com/NethermindEth/juno/core"
+)
+
+const eventSavePoint = "pitchlake_event"
+
+// retryBudget bounds the time runWithPolicy sleeps between attempts, since it
+// runs inside Juno's synchronous NewBlock and RevertBlock callbacks
+const retryBudget = 5 * time.Second
+
+// runWithPolicy runs a block-level operation that rolls back its own transaction
+// on failure. Under the retry policy it is retried with exponential backoff, for
+// at most retryBudget in total.
+func (p *pitchlakePlugin) runWithPolicy(action string, fn func() error) error {
+	attempts := 1
+	if p.errorPolicy.Mode == config.ErrorPolicyRetry {
+		attempts = p.errorPolicy.RetryAttempts
+	}
+	backoff := p.errorPolicy.RetryBackoff
+
+	var err error
+	var slept time.Duration
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt == attempts || slept >= retryBudget {
+			break
+		}
+		delay := min(backoff, retryBudget-slept)
+		p.log.Printf("%s failed (attempt %d/%d), retrying in %s: %v", action, attempt, attempts, delay, err)
+		time.Sleep(delay)
+		slept += delay
+		backoff *= 2
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
+
+// haltedOp is the block or revert that failed under the error policy
+type haltedOp struct {
+	err error
+	// block is the block that failed to index, nil for a failed revert
+	block *core.Block
+	retry func() error
+}
+
+// haltOn halts indexing on an operation that failed under the error policy,
+// so no later block is applied on top of it. The checkpoint stays on the last
+// good block.
+func (p *pitchlakePlugin) haltOn(err error, block *core.Block, retry func() error) error {
+	p.halted = &haltedOp{err: err, block: block, retry: retry}
+	p.log.Printf("Halting indexing: %v", err)
+	return err
+}
+
+// resume retries the operation indexing halted on, before the next block or
+// revert is handled, so a transient failure clears without restarting the
+// node. It fails while that operation still does.
+func (p *pitchlakePlugin) resume() error {
+	if p.halted == nil {
+		return nil
+	}
+	halted := p.halted
+	p.halted = nil
+	p.log.Printf("Retrying the operation indexing halted on: %v", halted.err)
+	if err := halted.retry(); err != nil {
+		return fmt.Errorf("indexing halted: %w", err)
+	}
+	p.log.Printf("Resumed indexing")
+	return nil
+}
+
+// processEventWithPolicy runs each event the indexer handles in a savepoint.
+// Events that fail to decode are undone and quarantined under every policy,
+// since retrying cannot fix them; other failures are only quarantined under
+// the skip policy, and otherwise roll back the block.
+func (p *pitchlakePlugin) processEventWithPolicy(block *core.Block, txIndex, index int) error {
+	if !p.indexer.Handles(block.Receipts[txIndex].Events[index]) {
+		return nil
+	}
+	if err := p.db.SavePoint(eventSavePoint); err != nil {
+		return err
+	}
+	err := p.indexer.ProcessEvent(block, txIndex, index)
+	if err == nil {
+		return nil
+	}
+	var decodeErr *adaptors.DecodeError
+	isDecodeErr := errors.As(err, &decodeErr)
+	if !isDecodeErr && p.errorPolicy.Mode != config.ErrorPolicySkip {
+		return err
+	}
+	if rollbackErr := p.db.RollbackTo(eventSavePoint); rollbackErr != nil {
+		return fmt.Errorf("rolling back skipped event: %w (event error: %v)", rollbackErr, err)
+	}
+	p.indexer.UndoEvent()
+	return p.quarantineEvent(block, txIndex, index, err)
+}
+
+// quarantineEvent stores the failing event in Quarantined_Events within the
+// block's transaction, along with the handler it failed in
+func (p *pitchlakePlugin) quarantineEvent(block *core.Block, txIndex, index int, err error) error {
+	receipt := block.Receipts[txIndex]
+	event := receipt.Events[index]
+	p.log.Printf(
+		"Quarantined event: block %d tx %s index %d from %s keys %v data %v: %v",
+		block.Number,
+		receipt.TransactionHash,
+		index,
+		event.From,
+		event.Keys,
+		event.Data,
+		err,
+	)
+	return p.indexer.Quarantine(block, txIndex, index, err)
+}
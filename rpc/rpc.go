@@ -0,0 +1,274 @@
+// Package rpc answers pitchlake_* JSON-RPC methods from the indexed database
+// using Juno's jsonrpc server, so dapps can use the same client and request
+// format they use for starknet_* calls.
+package rpc
+
+import (
+	"fmt"
+	"junoplugin/db"
+	"junoplugin/hexutil"
+	"junoplugin/models"
+	"log"
+	"net/http"
+
+	"github.com/NethermindEth/juno/jsonrpc"
+)
+
+const maxGoroutines = 32
+
+var (
+	// ErrContractNotFound mirrors the Starknet RPC error for an unknown vault or round
+	ErrContractNotFound = &jsonrpc.Error{Code: 20, Message: "Contract not found"}
+	// ErrBlockNotIndexed is returned for a block_number past the last indexed block
+	ErrBlockNotIndexed = &jsonrpc.Error{Code: 1000, Message: "Block not indexed yet"}
+)
+
+type Handler struct {
+	db *db.DB
+}
+
+func New(database *db.DB) *Handler {
+	return &Handler{db: database}
+}
+
+// Methods lists the pitchlake_* methods for registration on a jsonrpc.Server
+func (h *Handler) Methods() []jsonrpc.Method {
+	return []jsonrpc.Method{
+		{
+			Name:    "pitchlake_getVault",
+			Params:  []jsonrpc.Parameter{{Name: "vault_address"}, {Name: "block_number", Optional: true}},
+			Handler: h.GetVault,
+		},
+		{
+			Name:    "pitchlake_getRound",
+			Params:  []jsonrpc.Parameter{{Name: "round_address"}, {Name: "block_number", Optional: true}},
+			Handler: h.GetRound,
+		},
+		{
+			Name: "pitchlake_getLPPosition",
+			Params: []jsonrpc.Parameter{
+				{Name: "vault_address"},
+				{Name: "lp_address"},
+				{Name: "block_number", Optional: true},
+			},
+			Handler: h.GetLPPosition,
+		},
+		{
+			Name: "pitchlake_getBids",
+			Params: []jsonrpc.Parameter{
+				{Name: "round_address"},
+				{Name: "buyer_address", Optional: true},
+				{Name: "limit", Optional: true},
+				{Name: "offset", Optional: true},
+			},
+			Handler: h.GetBids,
+		},
+	}
+}
+
+// HTTPHandlers builds a jsonrpc server with the pitchlake_* methods and
+// returns its HTTP and WebSocket transports
+func (h *Handler) HTTPHandlers(logger *log.Logger) (http.Handler, http.Handler, error) {
+	server := jsonrpc.NewServer(maxGoroutines, simpleLogger{logger})
+	if err := server.RegisterMethods(h.Methods()...); err != nil {
+		return nil, nil, fmt.Errorf("registering pitchlake RPC methods: %w", err)
+	}
+	return jsonrpc.NewHTTP(server, simpleLogger{logger}), jsonrpc.NewWebsocket(server, simpleLogger{logger}), nil
+}
+
+func invalidAddress(name string, err error) *jsonrpc.Error {
+	return jsonrpc.Err(jsonrpc.InvalidParams, fmt.Sprintf("%s: %v", name, err))
+}
+
+func internalError(err error) *jsonrpc.Error {
+	return jsonrpc.Err(jsonrpc.InternalError, err.Error())
+}
+
+// checkIndexed rejects blocks the indexer has not reached, which would
+// otherwise silently return the latest state
+func (h *Handler) checkIndexed(blockNumber uint64) *jsonrpc.Error {
+	checkpoint, err := h.db.GetCheckpoint()
+	if err != nil {
+		return internalError(err)
+	}
+	if checkpoint == nil || blockNumber > checkpoint.BlockNumber {
+		return ErrBlockNotIndexed
+	}
+	return nil
+}
+
+// GetVault returns the vault's current state, or only its balances when
+// block_number is given
+func (h *Handler) GetVault(vaultAddress string, blockNumber *uint64) (any, *jsonrpc.Error) {
+	address, err := hexutil.NormalizeHexString(vaultAddress)
+	if err != nil {
+		return nil, invalidAddress("vault_address", err)
+	}
+	if blockNumber != nil {
+		if rpcErr := h.checkIndexed(*blockNumber); rpcErr != nil {
+			return nil, rpcErr
+		}
+		state, err := h.db.GetVaultStateAt(address, *blockNumber)
+		if err != nil {
+			return nil, internalError(err)
+		}
+		if state == nil {
+			return nil, ErrContractNotFound
+		}
+		return state, nil
+	}
+	vault, err := h.db.GetVault(address)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if vault == nil {
+		return nil, ErrContractNotFound
+	}
+	return vault, nil
+}
+
+// roundWithL1Request adds the Fossil request that delivered the round's
+// pricing data, null until it is fulfilled
+type roundWithL1Request struct {
+	*models.OptionRound
+	L1Request *models.L1Request `json:"l1_request"`
+}
+
+// withL1Request attaches the round's L1 request, leaving it out when it was
+// fulfilled after blockNumber
+func (h *Handler) withL1Request(round *models.OptionRound, blockNumber *uint64) (any, *jsonrpc.Error) {
+	request, err := h.db.GetL1Request(round.VaultAddress, round.RoundID)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if request != nil && blockNumber != nil && request.FulfilledBlock > *blockNumber {
+		request = nil
+	}
+	return roundWithL1Request{OptionRound: round, L1Request: request}, nil
+}
+
+// GetRound returns the round's current state, or its state at block_number,
+// with the L1 request that delivered its pricing data
+func (h *Handler) GetRound(roundAddress string, blockNumber *uint64) (any, *jsonrpc.Error) {
+	address, err := hexutil.NormalizeHexString(roundAddress)
+	if err != nil {
+		return nil, invalidAddress("round_address", err)
+	}
+	if blockNumber != nil {
+		if rpcErr := h.checkIndexed(*blockNumber); rpcErr != nil {
+			return nil, rpcErr
+		}
+		round, err := h.db.GetOptionRoundAt(address, *blockNumber)
+		if err != nil {
+			return nil, internalError(err)
+		}
+		if round == nil {
+			return nil, ErrContractNotFound
+		}
+		return h.withL1Request(round, blockNumber)
+	}
+	round, err := h.db.GetOptionRound(address)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if round == nil {
+		return nil, ErrContractNotFound
+	}
+	return h.withL1Request(round, nil)
+}
+
+// GetLPPosition returns null when the LP has no position in the vault
+func (h *Handler) GetLPPosition(vaultAddress, lpAddress string, blockNumber *uint64) (any, *jsonrpc.Error) {
+	vault, err := hexutil.NormalizeHexString(vaultAddress)
+	if err != nil {
+		return nil, invalidAddress("vault_address", err)
+	}
+	lp, err := hexutil.NormalizeHexString(lpAddress)
+	if err != nil {
+		return nil, invalidAddress("lp_address", err)
+	}
+	if blockNumber != nil {
+		if rpcErr := h.checkIndexed(*blockNumber); rpcErr != nil {
+			return nil, rpcErr
+		}
+		state, err := h.db.GetLPStateAt(vault, lp, *blockNumber)
+		if err != nil {
+			return nil, internalError(err)
+		}
+		if state == nil {
+			return nil, nil
+		}
+		return state, nil
+	}
+	position, err := h.db.GetLPPosition(vault, lp)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if position == nil {
+		return nil, nil
+	}
+	return position, nil
+}
+
+// GetBids returns the round's bids in clearing order, optionally only the
+// buyer's
+func (h *Handler) GetBids(roundAddress string, buyerAddress *string, limit, offset *int) (any, *jsonrpc.Error) {
+	round, err := hexutil.NormalizeHexString(roundAddress)
+	if err != nil {
+		return nil, invalidAddress("round_address", err)
+	}
+	page := db.Page{Limit: 100}
+	if limit != nil {
+		if *limit < 1 || *limit > 1000 {
+			return nil, jsonrpc.Err(jsonrpc.InvalidParams, "limit must be between 1 and 1000")
+		}
+		page.Limit = *limit
+	}
+	if offset != nil {
+		if *offset < 0 {
+			return nil, jsonrpc.Err(jsonrpc.InvalidParams, "offset must not be negative")
+		}
+		page.Offset = *offset
+	}
+	if buyerAddress != nil {
+		buyer, err := hexutil.NormalizeHexString(*buyerAddress)
+		if err != nil {
+			return nil, invalidAddress("buyer_address", err)
+		}
+		bids, err := h.db.ListBidsForBuyer(round, buyer, page)
+		if err != nil {
+			return nil, internalError(err)
+		}
+		return nonNil(bids), nil
+	}
+	bids, err := h.db.ListBidsForRound(round, page)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return nonNil(bids), nil
+}
+
+// nonNil makes an empty result encode as [] rather than null
+func nonNil(bids []models.Bid) []models.Bid {
+	if bids == nil {
+		return []models.Bid{}
+	}
+	return bids
+}
+
+// simpleLogger adapts the plugin's logger to Juno's utils.SimpleLogger
+type simpleLogger struct {
+	log *log.Logger
+}
+
+func (l simpleLogger) Debugw(msg string, keysAndValues ...any) {}
+func (l simpleLogger) Tracew(msg string, keysAndValues ...any) {}
+func (l simpleLogger) Infow(msg string, keysAndValues ...any) {
+	l.log.Println(append([]any{"RPC:", msg}, keysAndValues...)...)
+}
+func (l simpleLogger) Warnw(msg string, keysAndValues ...any) {
+	l.log.Println(append([]any{"RPC:", msg}, keysAndValues...)...)
+}
+func (l simpleLogger) Errorw(msg string, keysAndValues ...any) {
+	l.log.Println(append([]any{"RPC:", msg}, keysAndValues...)...)
+}
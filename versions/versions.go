@@ -0,0 +1,34 @@
+// Package versions names the Pitchlake contract versions the adaptors decode.
+// It is kept apart from adaptors, which links Juno's cgo core, so config can
+// validate versions without it.
+package versions
+
+import "sort"
+
+// Contract versions, one directory of ABIs under adaptors/abi/ each. A
+// version covers the vault and the option round classes released together.
+const (
+	V1 = "v1"
+	// V2 adds account_queued_liquidity_before to WithdrawalQueued
+	V2 = "v2"
+	// Default decodes the vault_hash class and vaults indexed before their
+	// class hash was stored
+	Default = V1
+)
+
+var known = map[string]bool{V1: true, V2: true}
+
+// Known reports whether name is a contract version
+func Known(name string) bool {
+	return known[name]
+}
+
+// All lists the known contract versions
+func All() []string {
+	names := make([]string, 0, len(known))
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}